@@ -0,0 +1,175 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// MarshalAIFF returns audio data as AIFF formatted data, which consists of FORM, COMM and SSND chunk.
+// The samples are stored in big endian, and the unsigned 8 bit samples are converted to signed.
+// IEEE float audio is not supported since it requires AIFF-C.
+func (v *File) MarshalAIFF() ([]byte, error) {
+	if v.SubFormat() != WAVE_FORMAT_PCM {
+		return nil, fmt.Errorf("%w: AIFF supports only linear PCM", ErrInvalidFormatTag)
+	}
+	if bits := v.BitsPerSample(); !(bits == 8 || bits == 16 || bits == 24 || bits == 32) {
+		return nil, fmt.Errorf("wav: invalid bits per sample (%v bit)", bits)
+	}
+
+	// SSND chunk has 8 bytes of offset and block size before the samples.
+	ssndSize := uint64(8 + len(v.data))
+	formSize := 4 + (8 + 18) + (8 + ssndSize + ssndSize%2)
+	if formSize > math.MaxUint32 {
+		return nil, fmt.Errorf("%w: FORM size (%v bytes) exceeds 4 GB", ErrDataTooLarge, formSize)
+	}
+
+	buf := new(bytes.Buffer)
+	buf.Grow(int(formSize) + 8)
+
+	buf.WriteString("FORM")
+	binary.Write(buf, binary.BigEndian, uint32(formSize))
+	buf.WriteString("AIFF")
+
+	buf.WriteString("COMM")
+	binary.Write(buf, binary.BigEndian, uint32(18))
+	binary.Write(buf, binary.BigEndian, v.channels)
+	binary.Write(buf, binary.BigEndian, uint32(v.Frames()))
+	binary.Write(buf, binary.BigEndian, v.bitsPerSample)
+	buf.Write(encodeExtended(float64(v.samplesPerSec)))
+
+	buf.WriteString("SSND")
+	binary.Write(buf, binary.BigEndian, uint32(ssndSize))
+	binary.Write(buf, binary.BigEndian, uint32(0)) // offset
+	binary.Write(buf, binary.BigEndian, uint32(0)) // block size
+	buf.Write(swapAIFFSamples(v.data, v.BitsPerSample()))
+	if ssndSize%2 == 1 {
+		buf.WriteByte(0)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalAIFF parses AIFF formatted data and returns it as *File.
+// Only the uncompressed AIFF is supported, AIFF-C is rejected.
+func UnmarshalAIFF(stream []byte) (*File, error) {
+	if len(stream) < 12 || string(stream[0:4]) != "FORM" || string(stream[8:12]) != "AIFF" {
+		return nil, fmt.Errorf("wav: not an AIFF stream")
+	}
+
+	var channels, bits int
+	var frames uint32
+	var rate float64
+	var comm bool
+	var ssnd []byte
+
+	for offset := 12; offset+8 <= len(stream); {
+		id := string(stream[offset : offset+4])
+		size := int(binary.BigEndian.Uint32(stream[offset+4 : offset+8]))
+		offset += 8
+		if size < 0 || offset+size > len(stream) {
+			return nil, fmt.Errorf("%w: %v chunk", ErrTruncatedData, id)
+		}
+		body := stream[offset : offset+size]
+
+		switch id {
+		case "COMM":
+			if size < 18 {
+				return nil, fmt.Errorf("%w: size '%v'", ErrInvalidFmtChunk, size)
+			}
+			channels = int(binary.BigEndian.Uint16(body[0:2]))
+			frames = binary.BigEndian.Uint32(body[2:6])
+			bits = int(binary.BigEndian.Uint16(body[6:8]))
+			rate = decodeExtended(body[8:18])
+			comm = true
+		case "SSND":
+			if size < 8 {
+				return nil, fmt.Errorf("%w: SSND chunk", ErrTruncatedData)
+			}
+			start := 8 + int(binary.BigEndian.Uint32(body[0:4]))
+			if start > size {
+				return nil, fmt.Errorf("%w: SSND chunk", ErrTruncatedData)
+			}
+			ssnd = body[start:]
+		}
+		offset += size + size%2
+	}
+	if !comm {
+		return nil, fmt.Errorf("%w: COMM chunk not found", ErrInvalidFmtChunk)
+	}
+	if ssnd == nil {
+		return nil, ErrDataChunkNotFound
+	}
+
+	if channels == 0 {
+		return nil, fmt.Errorf("%w: no channels", ErrInvalidFmtChunk)
+	}
+	if !(bits == 8 || bits == 16 || bits == 24 || bits == 32) {
+		return nil, fmt.Errorf("%w: sample size '%v'", ErrInvalidFmtChunk, bits)
+	}
+	if !(rate > 0 && rate <= math.MaxUint32) {
+		return nil, fmt.Errorf("%w: sample rate '%v'", ErrInvalidFmtChunk, rate)
+	}
+
+	audio, err := New(int(math.Round(rate)), bits, channels)
+	if err != nil {
+		return nil, err
+	}
+
+	length := int(frames) * audio.BlockAlign()
+	if length > len(ssnd) {
+		length = len(ssnd) - len(ssnd)%audio.BlockAlign()
+	}
+	audio.Write(swapAIFFSamples(ssnd[:length], bits))
+
+	return audio, nil
+}
+
+// swapAIFFSamples converts the samples between little endian (WAV) and big endian (AIFF).
+// The 8 bit samples are converted between unsigned (WAV) and signed (AIFF) instead.
+func swapAIFFSamples(data []byte, bits int) []byte {
+	swapped := make([]byte, len(data))
+	if bits == 8 {
+		for i, b := range data {
+			swapped[i] = b ^ 0x80
+		}
+		return swapped
+	}
+
+	width := bits / 8
+	for i := 0; i+width <= len(data); i += width {
+		for j := 0; j < width; j++ {
+			swapped[i+j] = data[i+width-1-j]
+		}
+	}
+
+	return swapped
+}
+
+// encodeExtended encodes f as 80 bit IEEE 754 extended precision float, which AIFF uses for the sample rate.
+func encodeExtended(f float64) []byte {
+	b := make([]byte, 10)
+	if f <= 0 {
+		return b
+	}
+
+	frac, exp := math.Frexp(f)
+	// f = frac * 2^exp where 0.5 <= frac < 1, and the mantissa has the explicit integer bit.
+	binary.BigEndian.PutUint16(b[0:2], uint16(16383+exp-1))
+	binary.BigEndian.PutUint64(b[2:10], uint64(math.Ldexp(frac, 64)))
+
+	return b
+}
+
+// decodeExtended decodes 80 bit IEEE 754 extended precision float.
+func decodeExtended(b []byte) float64 {
+	exp := int(binary.BigEndian.Uint16(b[0:2]) & 0x7fff)
+	mantissa := binary.BigEndian.Uint64(b[2:10])
+	f := math.Ldexp(float64(mantissa), exp-16383-63)
+	if b[0]&0x80 != 0 {
+		f = -f
+	}
+
+	return f
+}
@@ -0,0 +1,117 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+func TestMarshalAIFF(t *testing.T) {
+	filenames := []string{
+		"./testdata/sawtooth.wav",
+		"./testdata/8000Hz-8bit-1ch-odd.wav",
+		"./testdata/48000Hz-24bit-6ch-5.1.wav",
+	}
+	for _, filename := range filenames {
+		file, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		audio, err := Decode(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		stream, err := audio.MarshalAIFF()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(stream[0:4]) != "FORM" || string(stream[8:12]) != "AIFF" {
+			t.Fatalf("AIFF header must be written (%v)", filename)
+		}
+		if expected, actual := uint32(len(stream)-8), binary.BigEndian.Uint32(stream[4:8]); expected != actual {
+			t.Fatalf("expected: %v actual: %v (%v)", expected, actual, filename)
+		}
+
+		decoded, err := UnmarshalAIFF(stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.SamplesPerSec() != audio.SamplesPerSec() || decoded.Channels() != audio.Channels() || decoded.BitsPerSample() != audio.BitsPerSample() {
+			t.Fatalf("expected: %v actual: %v (%v)", audio, decoded, filename)
+		}
+		if !bytes.Equal(audio.Bytes(), decoded.Bytes()) {
+			t.Fatalf("audio samples must be preserved (%v)", filename)
+		}
+	}
+
+	// The first sample of sawtooth.wav is 0xc000 in big endian.
+	file, _ := ioutil.ReadFile("./testdata/sawtooth.wav")
+	audio, _ := Decode(file)
+	stream, _ := audio.MarshalAIFF()
+	if offset := bytes.Index(stream, []byte("SSND")) + 16; stream[offset] != 0xc0 || stream[offset+1] != 0x00 {
+		t.Fatalf("expected: c000 actual: %x", stream[offset:offset+2])
+	}
+
+	if _, err := UnmarshalAIFF(file); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+
+	// The bits per sample which is not a multiple of 8 is rejected.
+	binary.LittleEndian.PutUint16(file[34:36], 4)
+	audio, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = audio.MarshalAIFF(); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
+
+func TestUnmarshalAIFF_InvalidComm(t *testing.T) {
+	file, err := ioutil.ReadFile("./testdata/sawtooth.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := audio.MarshalAIFF()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// COMM chunk body starts at 20 bytes; sample size at +6 and sample rate at +8.
+	zeroBits := append([]byte{}, stream...)
+	binary.BigEndian.PutUint16(zeroBits[26:28], 0)
+	zeroRate := append([]byte{}, stream...)
+	copy(zeroRate[28:38], make([]byte, 10))
+	infiniteRate := append([]byte{}, stream...)
+	copy(infiniteRate[28:38], []byte{0x7f, 0xff, 0x80, 0, 0, 0, 0, 0, 0, 0})
+
+	for name, corrupted := range map[string][]byte{"zero bits": zeroBits, "zero rate": zeroRate, "infinite rate": infiniteRate} {
+		if _, err = UnmarshalAIFF(corrupted); !errors.Is(err, ErrInvalidFmtChunk) {
+			t.Fatalf("expected: %v actual: %v (%v)", ErrInvalidFmtChunk, err, name)
+		}
+	}
+	return
+}
+
+func TestExtended(t *testing.T) {
+	for _, rate := range []float64{8000, 22050, 44100, 48000, 192000} {
+		if actual := decodeExtended(encodeExtended(rate)); actual != rate {
+			t.Fatalf("expected: %v actual: %v", rate, actual)
+		}
+	}
+
+	// 44100 Hz in the AIFF files written by the other tools.
+	expected := []byte{0x40, 0x0e, 0xac, 0x44, 0, 0, 0, 0, 0, 0}
+	if actual := encodeExtended(44100); !bytes.Equal(expected, actual) {
+		t.Fatalf("expected: %x actual: %x", expected, actual)
+	}
+	return
+}
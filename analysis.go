@@ -0,0 +1,683 @@
+package wav
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// mono returns normalized samples which are averaged across channels.
+func (v *File) mono() []float64 {
+	channels := v.Channels()
+	frames := v.Frames()
+	f64 := v.Float64s()
+	m := make([]float64, frames)
+
+	for i := 0; i < frames; i++ {
+		sum := 0.0
+		for c := 0; c < channels; c++ {
+			sum += f64[i*channels+c]
+		}
+		m[i] = sum / float64(channels)
+	}
+
+	return m
+}
+
+// fft returns the discrete Fourier transform of x.
+// x is zero padded to the power of two length.
+func fft(x []float64) []complex128 {
+	n := 1
+	for n < len(x) {
+		n <<= 1
+	}
+
+	a := make([]complex128, n)
+	for i, f := range x {
+		a[i] = complex(f, 0)
+	}
+	transform(a)
+
+	return a
+}
+
+// transform computes the discrete Fourier transform of a in place.
+// The length of a must be the power of two.
+func transform(a []complex128) {
+	n := len(a)
+
+	// Bit reversal permutation.
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		w := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			wk := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				u := a[start+k]
+				t := wk * a[start+k+size/2]
+				a[start+k] = u + t
+				a[start+k+size/2] = u - t
+				wk *= w
+			}
+		}
+	}
+}
+
+// SpectralCentroid returns the amplitude weighted mean frequency in Hz.
+// The spectrum is computed from the whole audio which is mixed down to mono.
+// It returns 0 for the silent audio.
+func (v *File) SpectralCentroid() float64 {
+	spectrum := fft(v.mono())
+	n := len(spectrum)
+	binWidth := float64(v.SamplesPerSec()) / float64(n)
+
+	var weighted, total float64
+	for k := 0; k <= n/2; k++ {
+		amplitude := cmplx.Abs(spectrum[k])
+		weighted += float64(k) * binWidth * amplitude
+		total += amplitude
+	}
+	if total == 0 {
+		return 0
+	}
+
+	return weighted / total
+}
+
+// ZeroCrossingRate returns the fraction of the adjacent sample pairs whose sign changes.
+// It is computed from the audio which is mixed down to mono.
+func (v *File) ZeroCrossingRate() float64 {
+	m := v.mono()
+	if len(m) < 2 {
+		return 0
+	}
+
+	crossings := 0
+	for i := 1; i < len(m); i++ {
+		if (m[i-1] >= 0) != (m[i] >= 0) {
+			crossings++
+		}
+	}
+
+	return float64(crossings) / float64(len(m)-1)
+}
+
+// EstimatePitch returns the fundamental frequency in Hz estimated by autocorrelation.
+// The estimation uses up to 200 milliseconds at the center of the audio which is mixed down to mono,
+// and it detects the frequency between 50 Hz and 5000 Hz.
+func (v *File) EstimatePitch() (float64, error) {
+	const minFreq = 50.0
+	const maxFreq = 5000.0
+
+	rate := float64(v.SamplesPerSec())
+	m := v.mono()
+	size := int(rate / 5)
+	if size > len(m) {
+		size = len(m)
+	}
+	start := (len(m) - size) / 2
+	x := m[start : start+size]
+
+	minLag := int(rate / maxFreq)
+	if minLag < 2 {
+		minLag = 2
+	}
+	maxLag := int(rate / minFreq)
+	if maxLag > size/2 {
+		maxLag = size / 2
+	}
+	if maxLag <= minLag {
+		return 0, fmt.Errorf("wav: audio is too short to estimate pitch")
+	}
+
+	energy := 0.0
+	for _, f := range x {
+		energy += f * f
+	}
+	if math.Sqrt(energy/float64(size)) < 1e-3 {
+		return 0, fmt.Errorf("wav: audio is too quiet to estimate pitch")
+	}
+
+	r := make([]float64, maxLag+2)
+	for lag := minLag - 1; lag <= maxLag+1; lag++ {
+		sum := 0.0
+		for i := 0; i+lag < size; i++ {
+			sum += x[i] * x[i+lag]
+		}
+		r[lag] = sum / float64(size-lag)
+	}
+
+	peak := 0.0
+	for lag := minLag; lag <= maxLag; lag++ {
+		if r[lag] > peak {
+			peak = r[lag]
+		}
+	}
+	if peak <= 0 {
+		return 0, fmt.Errorf("wav: audio has no periodicity")
+	}
+
+	// Pick the shortest period to avoid octave errors.
+	for lag := minLag; lag <= maxLag; lag++ {
+		if r[lag] < 0.9*peak || r[lag] < r[lag-1] || r[lag] < r[lag+1] {
+			continue
+		}
+
+		delta := 0.0
+		if d := r[lag-1] - 2*r[lag] + r[lag+1]; d != 0 {
+			delta = 0.5 * (r[lag-1] - r[lag+1]) / d
+		}
+
+		return rate / (float64(lag) + delta), nil
+	}
+
+	return 0, fmt.Errorf("wav: audio has no periodicity")
+}
+
+// GuessSampleRate guesses the sample rate of headerless audio samples from candidates.
+// The data is interpreted at each candidate rate, and the rate whose estimated pitch is closest to
+// the equal tempered scale (A4 = 440 Hz) wins. When the pitch lands on the scale at several rates,
+// such as the rates an octave apart, the one whose pitch is closer to A4 is preferred.
+//
+// Note that the guess assumes the dominant pitch of the audio is around A4 and in tune with it.
+// A single tone is valid at any rate, so the other tones are often misjudged. For example,
+// 880 Hz or 1 kHz sampled at 44100 Hz is guessed as 22050 Hz, where its pitch is closer to A4.
+// It is a last resort for tonal audio of unknown rate, and it is not reliable for speech or noise.
+func GuessSampleRate(data []byte, bits, channels int, candidates []int) (int, error) {
+	best, bestScore := 0, math.Inf(1)
+
+	for _, rate := range candidates {
+		audio, err := New(rate, bits, channels)
+		if err != nil {
+			return 0, err
+		}
+		if channels <= 0 || len(data)%audio.BlockAlign() != 0 {
+			return 0, fmt.Errorf("wav: size of data (%v bytes) is not a multiple of block align", len(data))
+		}
+		audio.data = data
+		audio.length = uint32(len(data))
+
+		pitch, err := audio.EstimatePitch()
+		if err != nil {
+			continue
+		}
+
+		semitones := 12 * math.Log2(pitch/440)
+		score := math.Abs(semitones-math.Round(semitones)) * 2
+		score += math.Abs(semitones) / 24
+		if score < bestScore {
+			best, bestScore = rate, score
+		}
+	}
+	if best == 0 {
+		return 0, fmt.Errorf("wav: failed to guess sample rate")
+	}
+
+	return best, nil
+}
+
+// TruePeak returns the maximum absolute amplitude of the audio reconstructed at 4x oversampling.
+// Unlike the sample peak, it detects the inter-sample peaks, so it can exceed 1.0.
+// The samples are interpolated with Hann windowed sinc filter.
+func (v *File) TruePeak() float64 {
+	const factor = 4
+	const taps = 16
+
+	kernel := func(x float64) float64 {
+		if x == 0 {
+			return 1
+		}
+		if math.Abs(x) >= taps {
+			return 0
+		}
+		window := 0.5 * (1 + math.Cos(math.Pi*x/taps))
+		return math.Sin(math.Pi*x) / (math.Pi * x) * window
+	}
+
+	channels := v.Channels()
+	frames := v.Frames()
+	f64 := v.Float64s()
+	peak := 0.0
+
+	for c := 0; c < channels; c++ {
+		for i := 0; i < frames; i++ {
+			peak = math.Max(peak, math.Abs(f64[i*channels+c]))
+
+			for p := 1; p < factor; p++ {
+				t := float64(i) + float64(p)/factor
+				sum := 0.0
+				for k := i - taps + 1; k <= i+taps; k++ {
+					if k < 0 || k >= frames {
+						continue
+					}
+					sum += f64[k*channels+c] * kernel(t-float64(k))
+				}
+				peak = math.Max(peak, math.Abs(sum))
+			}
+		}
+	}
+
+	return peak
+}
+
+// WaveformPeaks splits the audio into buckets equal ranges and returns the minimum and maximum
+// amplitude of each range, which is useful for drawing the waveform. The amplitude of the frame is
+// the sum of the normalized samples across channels. If buckets is larger than the number of frames,
+// the ranges share frames so that the result always has buckets elements.
+func (v *File) WaveformPeaks(buckets int) [][2]float64 {
+	if buckets <= 0 {
+		return [][2]float64{}
+	}
+
+	channels := v.Channels()
+	frames := v.Frames()
+	f64 := v.Float64s()
+	peaks := make([][2]float64, buckets)
+
+	for b := range peaks {
+		start := b * frames / buckets
+		end := (b + 1) * frames / buckets
+		if end <= start {
+			end = start + 1
+		}
+		if end > frames {
+			continue
+		}
+
+		min, max := math.Inf(1), math.Inf(-1)
+		for i := start; i < end; i++ {
+			sum := 0.0
+			for c := 0; c < channels; c++ {
+				sum += f64[i*channels+c]
+			}
+			min = math.Min(min, sum)
+			max = math.Max(max, sum)
+		}
+		peaks[b] = [2]float64{min, max}
+	}
+
+	return peaks
+}
+
+// EffectiveBits returns the number of bits which actually carry information.
+// For example, 24 bit audio which is upconverted from 16 bit audio returns 16,
+// so that it can be downconverted to 16 bit without loss. The silent audio returns 0.
+func (v *File) EffectiveBits() int {
+	if v.SubFormat() == WAVE_FORMAT_IEEE_FLOAT {
+		return v.BitsPerSample()
+	}
+
+	var used uint32
+	for _, s := range v.Int32s() {
+		used |= uint32(s)
+	}
+	if used == 0 {
+		return 0
+	}
+
+	return 32 - bits.TrailingZeros32(used)
+}
+
+// DistinctSampleValues returns the number of unique sample values in the native bit depth.
+// The small number indicates heavily quantized or constant audio.
+func (v *File) DistinctSampleValues() int {
+	if v.SubFormat() == WAVE_FORMAT_IEEE_FLOAT {
+		values := map[float64]struct{}{}
+		for _, f := range v.Float64s() {
+			values[f] = struct{}{}
+		}
+		return len(values)
+	}
+
+	values := map[int32]struct{}{}
+	for _, s := range v.Int32s() {
+		values[s] = struct{}{}
+	}
+	return len(values)
+}
+
+// IsSilent reports whether the absolute normalized value of every sample is below threshold.
+// The audio which contains no samples is silent.
+func (v *File) IsSilent(threshold float64) bool {
+	for _, f := range v.Float64s() {
+		if math.Abs(f) >= threshold {
+			return false
+		}
+	}
+	return true
+}
+
+// AudioHash returns SHA-256 digest of the audio format and the audio samples.
+// Metadata such as the chunks other than fmt and data does not affect the digest,
+// so that the identical audio with different tags has the same digest.
+func (v *File) AudioHash() [32]byte {
+	h := sha256.New()
+
+	binary.Write(h, binary.LittleEndian, v.SubFormat())
+	binary.Write(h, binary.LittleEndian, v.channels)
+	binary.Write(h, binary.LittleEndian, v.samplesPerSec)
+	binary.Write(h, binary.LittleEndian, v.bitsPerSample)
+	h.Write(v.data)
+
+	var sum [32]byte
+	copy(sum[:], h.Sum(nil))
+
+	return sum
+}
+
+// HeadroomDB returns the gain in decibels which can be applied before the loudest sample hits full scale,
+// that is 20*log10(1/peak). The silent audio returns +Inf.
+func (v *File) HeadroomDB() float64 {
+	peak := 0.0
+	for _, f := range v.Float64s() {
+		peak = math.Max(peak, math.Abs(f))
+	}
+	if peak == 0 {
+		return math.Inf(1)
+	}
+
+	return 20 * math.Log10(1/peak)
+}
+
+// BoundaryDiscontinuity returns the absolute normalized jump between the last frame and the first frame,
+// which is heard as a click when the audio is looped. For the multichannel audio, the largest jump among
+// the channels is returned. The audio which contains no samples returns 0.
+func (v *File) BoundaryDiscontinuity() float64 {
+	channels := v.Channels()
+	frames := v.Frames()
+	if frames == 0 {
+		return 0
+	}
+
+	f64 := v.Float64s()
+	last := (frames - 1) * channels
+	jump := 0.0
+	for c := 0; c < channels; c++ {
+		jump = math.Max(jump, math.Abs(f64[last+c]-f64[c]))
+	}
+
+	return jump
+}
+
+// Energy returns the sum of the squared normalized samples across all channels.
+// It relates to RMS as RMS = sqrt(Energy / Samples).
+func (v *File) Energy() float64 {
+	energy := 0.0
+	for _, f := range v.Float64s() {
+		energy += f * f
+	}
+	return energy
+}
+
+// RMS returns the root mean square of the normalized samples across all channels.
+// The audio which contains no samples returns 0.
+func (v *File) RMS() float64 {
+	samples := v.Samples()
+	if samples == 0 {
+		return 0
+	}
+	return math.Sqrt(v.Energy() / float64(samples))
+}
+
+// ChannelDelaySamples returns the offset in frames of channel b relative to channel a, which is found by cross-correlation.
+// The positive value means that b is delayed behind a. It returns 0 if either channel is out of range.
+func (v *File) ChannelDelaySamples(a, b int) int {
+	channels := v.Channels()
+	if a < 0 || a >= channels || b < 0 || b >= channels || a == b {
+		return 0
+	}
+
+	frames := v.Frames()
+	f64 := v.Float64s()
+	x := make([]float64, 2*frames)
+	y := make([]float64, 2*frames)
+	for i := 0; i < frames; i++ {
+		x[i] = f64[i*channels+a]
+		y[i] = f64[i*channels+b]
+	}
+
+	// The cross-correlation is the inverse transform of conj(X)Y,
+	// and the inverse transform is computed as conj(transform(conj(...))).
+	X, Y := fft(x), fft(y)
+	r := make([]complex128, len(X))
+	for k := range r {
+		r[k] = cmplx.Conj(cmplx.Conj(X[k]) * Y[k])
+	}
+	transform(r)
+
+	n := len(r)
+	delay, peak := 0, math.Inf(-1)
+	for k := range r {
+		// The negative lag is wrapped around to the end.
+		lag := k
+		if k > n/2 {
+			lag = k - n
+		}
+		if lag <= -frames || lag >= frames {
+			continue
+		}
+		if c := real(r[k]); c > peak {
+			delay, peak = lag, c
+		}
+	}
+
+	return delay
+}
+
+// fullScale returns the largest positive value in the native bit depth, left justified to 32 bit as Int32s.
+// The negative full scale includes -fullScale() as well as the minimum value, since many encoders clip symmetrically.
+// IEEE float audio is scaled by Int32s so that ±1.0 is the full scale regardless of the bit depth.
+func (v *File) fullScale() int32 {
+	bits := v.BitsPerSample()
+	if v.SubFormat() == WAVE_FORMAT_IEEE_FLOAT || bits <= 0 || bits > 32 {
+		return math.MaxInt32
+	}
+	shift := uint(32 - bits)
+	return int32(math.MaxInt32) >> shift << shift
+}
+
+// ClippingRun represents consecutive clipped frames.
+type ClippingRun struct {
+	Start  int // The first clipped frame.
+	Length int // The number of clipped frames.
+}
+
+// ClippingRuns returns the runs of consecutive frames in which any channel is at full scale.
+// The long runs indicate the heavy clipping which should be repaired first.
+func (v *File) ClippingRuns() []ClippingRun {
+	high := v.fullScale()
+	channels := v.Channels()
+	s32 := v.Int32s()
+	frames := len(s32) / channels
+	runs := []ClippingRun{}
+
+	for i := 0; i < frames; i++ {
+		clipped := false
+		for c := 0; c < channels; c++ {
+			if s := s32[i*channels+c]; s >= high || s <= -high {
+				clipped = true
+				break
+			}
+		}
+		if !clipped {
+			continue
+		}
+		if n := len(runs); n > 0 && runs[n-1].Start+runs[n-1].Length == i {
+			runs[n-1].Length++
+		} else {
+			runs = append(runs, ClippingRun{Start: i, Length: 1})
+		}
+	}
+
+	return runs
+}
+
+// ActiveRatio returns the fraction of the frames whose level exceeds thresholdDB (dBFS).
+// The level is the normalized absolute amplitude of the loudest channel in each frame.
+// The silent audio returns 0 and the audio which contains no samples returns 0 as well.
+func (v *File) ActiveRatio(thresholdDB float64) float64 {
+	frames := v.Frames()
+	if frames == 0 {
+		return 0
+	}
+
+	threshold := math.Pow(10, thresholdDB/20)
+	channels := v.Channels()
+	f64 := v.Float64s()
+	active := 0
+
+	for i := 0; i < frames; i++ {
+		for c := 0; c < channels; c++ {
+			if math.Abs(f64[i*channels+c]) > threshold {
+				active++
+				break
+			}
+		}
+	}
+
+	return float64(active) / float64(frames)
+}
+
+// Polarity returns the signed bias of the large transients between -1.0 and 1.0.
+// The samples whose absolute amplitude is at least half of the peak are taken as the transients,
+// and the positive value means that they lean positive. It is useful to align the polarity of
+// the kick drum microphones. The silent audio returns 0.
+func (v *File) Polarity() float64 {
+	f64 := v.Float64s()
+	peak := 0.0
+	for _, f := range f64 {
+		peak = math.Max(peak, math.Abs(f))
+	}
+	if peak == 0 {
+		return 0
+	}
+
+	sum, total := 0.0, 0.0
+	for _, f := range f64 {
+		if math.Abs(f) >= peak/2 {
+			sum += f
+			total += math.Abs(f)
+		}
+	}
+
+	return sum / total
+}
+
+// EstimateBPM returns the tempo in beats per minute between 60 and 200.
+// The onsets are detected from the rise of the energy in every 10 milliseconds of the audio which is mixed down to mono,
+// and the tempo is the period which maximizes the autocorrelation of the onset envelope.
+// It returns an error if the audio is shorter than 4 beats at 60 BPM or it is not rhythmic.
+func (v *File) EstimateBPM() (float64, error) {
+	const minBPM = 60.0
+	const maxBPM = 200.0
+
+	rate := float64(v.SamplesPerSec())
+	hop := int(math.Round(rate / 100))
+	if hop < 1 {
+		hop = 1
+	}
+	m := v.mono()
+	n := len(m) / hop
+
+	// The lags in hops which correspond to maxBPM and minBPM.
+	minLag := int(60 * rate / (maxBPM * float64(hop)))
+	maxLag := int(math.Ceil(60 * rate / (minBPM * float64(hop))))
+	if minLag < 2 {
+		minLag = 2
+	}
+	if n < 4*maxLag {
+		return 0, fmt.Errorf("wav: audio is too short to estimate tempo")
+	}
+
+	energy := make([]float64, n)
+	for k := range energy {
+		for _, f := range m[k*hop : (k+1)*hop] {
+			energy[k] += f * f
+		}
+	}
+
+	onset := make([]float64, n)
+	mean := 0.0
+	for k := 1; k < n; k++ {
+		onset[k] = math.Max(0, energy[k]-energy[k-1])
+		mean += onset[k]
+	}
+	mean /= float64(n)
+	for k := range onset {
+		onset[k] -= mean
+	}
+
+	r := make([]float64, maxLag+2)
+	for lag := 0; lag <= maxLag+1; lag++ {
+		sum := 0.0
+		for k := 0; k+lag < n; k++ {
+			sum += onset[k] * onset[k+lag]
+		}
+		r[lag] = sum / float64(n-lag)
+	}
+	if r[0] <= 0 {
+		return 0, fmt.Errorf("wav: audio has no onsets")
+	}
+
+	peak := 0.0
+	for lag := minLag; lag <= maxLag; lag++ {
+		peak = math.Max(peak, r[lag])
+	}
+	if peak < 0.3*r[0] {
+		return 0, fmt.Errorf("wav: audio is not rhythmic")
+	}
+
+	// Pick the shortest period to avoid picking the half tempo.
+	for lag := minLag; lag <= maxLag; lag++ {
+		if r[lag] < 0.9*peak || r[lag] < r[lag-1] || r[lag] < r[lag+1] {
+			continue
+		}
+
+		delta := 0.0
+		if d := r[lag-1] - 2*r[lag] + r[lag+1]; d != 0 {
+			delta = 0.5 * (r[lag-1] - r[lag+1]) / d
+		}
+
+		return 60 * rate / ((float64(lag) + delta) * float64(hop)), nil
+	}
+
+	return 0, fmt.Errorf("wav: audio is not rhythmic")
+}
+
+// ToMLInput returns the audio which is mixed down to mono, resampled to targetRate and normalized as Float32s.
+// It is the common input of the machine learning feature extractors. The audio is resampled by ResampleSinc
+// with 32 taps only when targetRate differs from the sample rate.
+func (v *File) ToMLInput(targetRate int) ([]float32, error) {
+	if targetRate <= 0 {
+		return nil, fmt.Errorf("wav: invalid sample rate (%v)", targetRate)
+	}
+
+	// The mono audio is kept in 64 bit float to avoid the quantization before resampling.
+	audio, err := New(v.SamplesPerSec(), 64, 1)
+	if err != nil {
+		return nil, err
+	}
+	audio.subFormat = WAVE_FORMAT_IEEE_FLOAT
+	audio.setFloat64s(v.mono())
+
+	if targetRate != v.SamplesPerSec() {
+		if audio, err = audio.ResampleSinc(targetRate, 32); err != nil {
+			return nil, err
+		}
+	}
+
+	return audio.Float32s(), nil
+}
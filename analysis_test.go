@@ -0,0 +1,467 @@
+package wav
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSpectralCentroid(t *testing.T) {
+	low := newTestFile(t, 44100, 2, 4410, sine(200, 44100))
+	high := newTestFile(t, 44100, 2, 4410, sine(5000, 44100))
+
+	lowCentroid := low.SpectralCentroid()
+	highCentroid := high.SpectralCentroid()
+
+	if lowCentroid >= highCentroid {
+		t.Fatalf("expected: %v < %v", lowCentroid, highCentroid)
+	}
+
+	silent, _ := New(44100, 16, 1)
+	if c := silent.SpectralCentroid(); c != 0 {
+		t.Fatalf("expected: 0 actual: %v", c)
+	}
+	return
+}
+
+func TestZeroCrossingRate(t *testing.T) {
+	low := newTestFile(t, 44100, 1, 4410, sine(100, 44100))
+	high := newTestFile(t, 44100, 1, 4410, sine(8000, 44100))
+
+	lowRate := low.ZeroCrossingRate()
+	highRate := high.ZeroCrossingRate()
+
+	if lowRate >= highRate {
+		t.Fatalf("expected: %v < %v", lowRate, highRate)
+	}
+	return
+}
+
+func TestEstimatePitch(t *testing.T) {
+	audio := newTestFile(t, 44100, 2, 44100, sine(440, 44100))
+
+	pitch, err := audio.EstimatePitch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(pitch-440) > 3 {
+		t.Fatalf("expected: 440 actual: %v", pitch)
+	}
+
+	silent := newTestFile(t, 44100, 1, 44100, func(i, c int) float64 { return 0 })
+	if _, err = silent.EstimatePitch(); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
+
+func TestGuessSampleRate(t *testing.T) {
+	audio := newTestFile(t, 44100, 1, 44100, sine(440, 44100))
+	candidates := []int{22050, 44100, 48000, 96000}
+
+	rate, err := GuessSampleRate(audio.Bytes(), 16, 1, candidates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate != 44100 {
+		t.Fatalf("expected: 44100 actual: %v", rate)
+	}
+
+	audio = newTestFile(t, 48000, 2, 48000, sine(440, 48000))
+	if rate, err = GuessSampleRate(audio.Bytes(), 16, 2, candidates); err != nil {
+		t.Fatal(err)
+	}
+	if rate != 48000 {
+		t.Fatalf("expected: 48000 actual: %v", rate)
+	}
+
+	if _, err = GuessSampleRate(audio.Bytes()[1:], 16, 1, candidates); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+
+	// The tone off the scale is misjudged as documented, 1 kHz at 44100 Hz is heard as 500 Hz at 22050 Hz.
+	audio = newTestFile(t, 44100, 1, 44100, sine(1000, 44100))
+	if rate, err = GuessSampleRate(audio.Bytes(), 16, 1, candidates); err != nil {
+		t.Fatal(err)
+	}
+	if rate != 22050 {
+		t.Fatalf("expected: 22050 actual: %v", rate)
+	}
+	return
+}
+
+func TestTruePeak(t *testing.T) {
+	// The sine wave at a quarter of the sample rate with 45 degrees phase
+	// is sampled at 0.707 of its true amplitude.
+	audio := newTestFile(t, 44100, 2, 4410, func(i, c int) float64 {
+		return 0.9 * math.Sin(math.Pi/2*float64(i)+math.Pi/4)
+	})
+
+	samplePeak := 0.0
+	for _, f := range audio.Float64s() {
+		samplePeak = math.Max(samplePeak, math.Abs(f))
+	}
+
+	truePeak := audio.TruePeak()
+	if truePeak <= samplePeak {
+		t.Fatalf("expected: %v > %v", truePeak, samplePeak)
+	}
+	if math.Abs(truePeak-0.9) > 0.02 {
+		t.Fatalf("expected: 0.9 actual: %v", truePeak)
+	}
+	return
+}
+
+func TestWaveformPeaks(t *testing.T) {
+	tone := sine(440, 8000)
+	audio := newTestFile(t, 8000, 2, 8000, func(i, c int) float64 {
+		// The second half is silent.
+		if i < 4000 {
+			return tone(i, c)
+		}
+		return 0
+	})
+
+	peaks := audio.WaveformPeaks(100)
+	if len(peaks) != 100 {
+		t.Fatalf("expected: 100 actual: %v", len(peaks))
+	}
+	if peaks[0][0] > -0.9 || peaks[0][1] < 0.9 {
+		t.Fatalf("expected: about (-1, 1) actual: %v", peaks[0])
+	}
+	if peaks[99] != [2]float64{0, 0} {
+		t.Fatalf("expected: (0, 0) actual: %v", peaks[99])
+	}
+
+	if peaks = audio.WaveformPeaks(100000); len(peaks) != 100000 {
+		t.Fatalf("expected: 100000 actual: %v", len(peaks))
+	}
+	empty, _ := New(8000, 16, 1)
+	if peaks = empty.WaveformPeaks(10); len(peaks) != 10 || peaks[0] != [2]float64{0, 0} {
+		t.Fatalf("expected: 10 silent buckets actual: %v", peaks)
+	}
+	return
+}
+
+func TestEffectiveBits(t *testing.T) {
+	audio, err := newTestFile(t, 44100, 2, 4410, sine(440, 44100)).ConvertBitDepth(24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audio.BitsPerSample() != 24 {
+		t.Fatalf("expected: 24 actual: %v", audio.BitsPerSample())
+	}
+	if bits := audio.EffectiveBits(); bits != 16 {
+		t.Fatalf("expected: 16 actual: %v", bits)
+	}
+
+	if err = audio.BitCrush(8); err != nil {
+		t.Fatal(err)
+	}
+	if bits := audio.EffectiveBits(); bits != 8 {
+		t.Fatalf("expected: 8 actual: %v", bits)
+	}
+	return
+}
+
+func TestDistinctSampleValues(t *testing.T) {
+	audio := newTestFile(t, 44100, 1, 44100, sine(440, 44100))
+
+	if n := audio.DistinctSampleValues(); n < 1000 {
+		t.Fatalf("expected: more than 1000 actual: %v", n)
+	}
+	if err := audio.BitCrush(4); err != nil {
+		t.Fatal(err)
+	}
+	if n := audio.DistinctSampleValues(); n > 16 {
+		t.Fatalf("expected: at most 16 actual: %v", n)
+	}
+
+	constant := newTestFile(t, 44100, 2, 100, func(i, c int) float64 { return 0.5 })
+	if n := constant.DistinctSampleValues(); n != 1 {
+		t.Fatalf("expected: 1 actual: %v", n)
+	}
+	return
+}
+
+func TestIsSilent(t *testing.T) {
+	silent := newTestFile(t, 8000, 2, 8000, func(i, c int) float64 { return 0 })
+	tone := newTestFile(t, 8000, 2, 8000, sine(440, 8000))
+	empty, _ := New(8000, 16, 2)
+
+	if !silent.IsSilent(0.001) {
+		t.Fatalf("expected: true actual: false")
+	}
+	if tone.IsSilent(0.001) {
+		t.Fatalf("expected: false actual: true")
+	}
+	if !empty.IsSilent(0.001) {
+		t.Fatalf("expected: true actual: false")
+	}
+	return
+}
+
+func TestAudioHash(t *testing.T) {
+	file, err := ioutil.ReadFile("./testdata/sawtooth.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Insert LIST chunk between fmt chunk and data chunk.
+	list := []byte("LIST\x0c\x00\x00\x00INFOINAM\x00\x00\x00\x00")
+	tagged := append(append(append([]byte{}, file[:36]...), list...), file[36:]...)
+	binary.LittleEndian.PutUint32(tagged[4:8], uint32(len(tagged)-8))
+
+	a, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Decode(tagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.AudioHash() != b.AudioHash() {
+		t.Fatalf("identical audio must have the same hash")
+	}
+
+	b.SetSampleRate(22050)
+	if a.AudioHash() == b.AudioHash() {
+		t.Fatalf("different format must have different hash")
+	}
+	return
+}
+
+func TestHeadroomDB(t *testing.T) {
+	half := newTestFile(t, 44100, 2, 100, func(i, c int) float64 {
+		if i == 50 {
+			return -0.5
+		}
+		return 0.1
+	})
+	silent := newTestFile(t, 44100, 1, 100, func(i, c int) float64 {
+		return 0
+	})
+
+	if actual := half.HeadroomDB(); math.Abs(actual-6.02) > 0.01 {
+		t.Fatalf("expected: 6.02 actual: %v", actual)
+	}
+	if actual := silent.HeadroomDB(); !math.IsInf(actual, 1) {
+		t.Fatalf("expected: +Inf actual: %v", actual)
+	}
+	return
+}
+
+func TestBoundaryDiscontinuity(t *testing.T) {
+	// The ramp from -0.8 to 0.8 jumps by 1.6 when it is looped.
+	ramp := newTestFile(t, 8000, 2, 800, func(i, c int) float64 {
+		return -0.8 + 1.6*float64(i)/799
+	})
+	loop := newTestFile(t, 8000, 2, 800, sine(10, 8000))
+	empty, _ := New(8000, 16, 2)
+
+	if actual := ramp.BoundaryDiscontinuity(); math.Abs(actual-1.6) > 1e-3 {
+		t.Fatalf("expected: 1.6 actual: %v", actual)
+	}
+	if actual := loop.BoundaryDiscontinuity(); actual > 0.01 {
+		t.Fatalf("expected: about 0 actual: %v", actual)
+	}
+	if actual := empty.BoundaryDiscontinuity(); actual != 0 {
+		t.Fatalf("expected: 0 actual: %v", actual)
+	}
+	return
+}
+
+func TestEnergy(t *testing.T) {
+	file, err := ioutil.ReadFile("./testdata/sawtooth.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rms := audio.RMS()
+	expected := rms * rms * float64(audio.Samples())
+	if actual := audio.Energy(); math.Abs(actual-expected) > 1e-6*expected {
+		t.Fatalf("expected: %v actual: %v", expected, actual)
+	}
+
+	// The sine wave with amplitude 0.5 has RMS of 0.5/sqrt(2).
+	tone := newTestFile(t, 44100, 2, 44100, sine(440, 44100))
+	if actual := tone.RMS(); math.Abs(actual-0.5/math.Sqrt2) > 1e-3 {
+		t.Fatalf("expected: %v actual: %v", 0.5/math.Sqrt2, actual)
+	}
+
+	empty, _ := New(44100, 16, 2)
+	if empty.Energy() != 0 || empty.RMS() != 0 {
+		t.Fatalf("expected: 0 actual: %v %v", empty.Energy(), empty.RMS())
+	}
+	return
+}
+
+func TestClippingRuns(t *testing.T) {
+	audio := newTestFile(t, 8000, 2, 1000, func(i, c int) float64 {
+		switch {
+		case i >= 100 && i < 105 && c == 0:
+			return 1
+		case i >= 500 && i < 520 && c == 1:
+			return -1
+		}
+		return 0.5
+	})
+
+	expected := []ClippingRun{{Start: 100, Length: 5}, {Start: 500, Length: 20}}
+	actual := audio.ClippingRuns()
+	if len(actual) != len(expected) {
+		t.Fatalf("expected: %v actual: %v", expected, actual)
+	}
+	for i := range expected {
+		if expected[i] != actual[i] {
+			t.Fatalf("[%v] expected: %v actual: %v", i, expected[i], actual[i])
+		}
+	}
+
+	clean := newTestFile(t, 8000, 1, 1000, sine(440, 8000))
+	if runs := clean.ClippingRuns(); len(runs) != 0 {
+		t.Fatalf("expected: [] actual: %v", runs)
+	}
+
+	// The full scale of IEEE float audio is 1.0 regardless of the bit depth.
+	file, err := ioutil.ReadFile("./testdata/44100Hz-64bit-1ch-float.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	float, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs := float.ClippingRuns(); len(runs) != 0 {
+		t.Fatalf("expected: [] actual: %v", runs)
+	}
+	return
+}
+
+func TestActiveRatio(t *testing.T) {
+	tone := sine(440, 8000)
+	half := newTestFile(t, 8000, 2, 8000, func(i, c int) float64 {
+		if i < 4000 {
+			return tone(i, c)
+		}
+		return 0
+	})
+	loud := newTestFile(t, 8000, 1, 8000, func(i, c int) float64 { return 0.5 })
+	silent := newTestFile(t, 8000, 1, 8000, func(i, c int) float64 { return 0 })
+
+	if actual := half.ActiveRatio(-40); math.Abs(actual-0.5) > 0.01 {
+		t.Fatalf("expected: 0.5 actual: %v", actual)
+	}
+	if actual := loud.ActiveRatio(-40); actual != 1 {
+		t.Fatalf("expected: 1 actual: %v", actual)
+	}
+	if actual := silent.ActiveRatio(-40); actual != 0 {
+		t.Fatalf("expected: 0 actual: %v", actual)
+	}
+	return
+}
+
+func TestPolarity(t *testing.T) {
+	// The impulse which kicks positive and rebounds negative every 100 frames.
+	impulse := func(i, c int) float64 {
+		switch i % 100 {
+		case 0:
+			return 0.9
+		case 1:
+			return -0.3
+		}
+		return 0.01
+	}
+	positive := newTestFile(t, 8000, 2, 1000, impulse)
+	negative := newTestFile(t, 8000, 2, 1000, func(i, c int) float64 {
+		return -impulse(i, c)
+	})
+	silent := newTestFile(t, 8000, 1, 1000, func(i, c int) float64 { return 0 })
+
+	if actual := positive.Polarity(); actual <= 0.5 {
+		t.Fatalf("expected: greater than 0.5 actual: %v", actual)
+	}
+	if actual := negative.Polarity(); actual >= -0.5 {
+		t.Fatalf("expected: less than -0.5 actual: %v", actual)
+	}
+	if actual := silent.Polarity(); actual != 0 {
+		t.Fatalf("expected: 0 actual: %v", actual)
+	}
+	return
+}
+
+func TestEstimateBPM(t *testing.T) {
+	click := func(bpm float64, samplesPerSec int) func(i, c int) float64 {
+		period := 60 / bpm * float64(samplesPerSec)
+		return func(i, c int) float64 {
+			// The decaying 1 kHz burst at every beat.
+			k := float64(i) - math.Floor(float64(i)/period)*period
+			return 0.8 * math.Exp(-k/40) * math.Sin(2*math.Pi*1000*k/float64(samplesPerSec))
+		}
+	}
+
+	for _, bpm := range []float64{90, 120, 128} {
+		audio := newTestFile(t, 8000, 2, 8000*12, click(bpm, 8000))
+
+		actual, err := audio.EstimateBPM()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(actual-bpm) > 1 {
+			t.Fatalf("expected: %v actual: %v", bpm, actual)
+		}
+	}
+
+	short := newTestFile(t, 8000, 1, 8000, click(120, 8000))
+	if _, err := short.EstimateBPM(); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+
+	r := rand.New(rand.NewSource(1))
+	noise := newTestFile(t, 8000, 1, 8000*12, func(i, c int) float64 {
+		return 0.5 * (r.Float64()*2 - 1)
+	})
+	if _, err := noise.EstimateBPM(); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
+
+func TestToMLInput(t *testing.T) {
+	audio := newTestFile(t, 44100, 2, 44100*2, sine(440, 44100))
+
+	if _, err := audio.ToMLInput(0); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+
+	f32, err := audio.ToMLInput(16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f32) != 16000*2 {
+		t.Fatalf("expected: %v actual: %v", 16000*2, len(f32))
+	}
+
+	// The sine wave with amplitude 0.5 has RMS of 0.5/sqrt(2).
+	sum := 0.0
+	for _, f := range f32 {
+		sum += float64(f) * float64(f)
+	}
+	if rms := math.Sqrt(sum / float64(len(f32))); math.Abs(rms-0.5/math.Sqrt2) > 0.01 {
+		t.Fatalf("expected: %v actual: %v", 0.5/math.Sqrt2, rms)
+	}
+
+	if f32, err = audio.ToMLInput(44100); err != nil {
+		t.Fatal(err)
+	}
+	if len(f32) != audio.Frames() {
+		t.Fatalf("expected: %v actual: %v", audio.Frames(), len(f32))
+	}
+	return
+}
@@ -0,0 +1,34 @@
+package wav
+
+import (
+	"encoding/binary"
+)
+
+// timeReferenceOffset is the offset of TimeReference field in bext chunk,
+// which follows Description, Originator, OriginatorReference, OriginationDate and OriginationTime.
+const timeReferenceOffset = 256 + 32 + 32 + 10 + 8
+
+// TimeReference returns the time reference of the broadcast audio extension (bext) chunk,
+// that is the number of samples since midnight of the first sample. It returns false if the audio has no bext chunk.
+// The operations which remove frames from the start, such as Delete, Split and LoudestRegion,
+// advance the time reference by the number of removed frames so that the audio stays in sync.
+func (v *File) TimeReference() (uint64, bool) {
+	if v.bext == nil || len(v.bext.data) < timeReferenceOffset+8 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint64(v.bext.data[timeReferenceOffset:]), true
+}
+
+// shiftTimeReference advances the time reference of bext chunk by frames.
+// The chunk may be shared with the other File, so it is copied before modification.
+func (v *File) shiftTimeReference(frames int) {
+	reference, ok := v.TimeReference()
+	if !ok || frames == 0 {
+		return
+	}
+
+	data := make([]byte, len(v.bext.data))
+	copy(data, v.bext.data)
+	binary.LittleEndian.PutUint64(data[timeReferenceOffset:], reference+uint64(frames))
+	v.bext = &rawChunk{id: v.bext.id, data: data}
+}
@@ -0,0 +1,68 @@
+package wav
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestTimeReference(t *testing.T) {
+	filename := "./testdata/44100Hz-16bit-1ch-bext.wav"
+	file, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One hour after midnight.
+	if reference, ok := audio.TimeReference(); !ok || reference != 158760000 {
+		t.Fatalf("expected: 158760000 actual: %v (%v)", reference, filename)
+	}
+
+	stream, err := Marshal(audio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(file, stream) {
+		t.Fatalf("bext chunk must be preserved (%v)", filename)
+	}
+	if audio.MarshalSize() != len(stream) {
+		t.Fatalf("expected: %v actual: %v (%v)", len(stream), audio.MarshalSize(), filename)
+	}
+
+	// Trim the first 10 milliseconds.
+	chunks, err := audio.Split(10 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = audio.Delete(0, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if reference, _ := audio.TimeReference(); reference != 158760000+441 {
+		t.Fatalf("expected: %v actual: %v (%v)", 158760000+441, reference, filename)
+	}
+	if reference, _ := chunks[0].TimeReference(); reference != 158760000 {
+		t.Fatalf("expected: 158760000 actual: %v (%v)", reference, filename)
+	}
+	if reference, _ := chunks[3].TimeReference(); reference != 158760000+441*3 {
+		t.Fatalf("expected: %v actual: %v (%v)", 158760000+441*3, reference, filename)
+	}
+
+	// Delete in the middle does not move the first frame.
+	if err = audio.Delete(10*time.Millisecond, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if reference, _ := audio.TimeReference(); reference != 158760000+441 {
+		t.Fatalf("expected: %v actual: %v (%v)", 158760000+441, reference, filename)
+	}
+
+	plain, _ := New(44100, 16, 1)
+	if _, ok := plain.TimeReference(); ok {
+		t.Fatalf("expected: false actual: true")
+	}
+	return
+}
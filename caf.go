@@ -0,0 +1,54 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// The format flags of linear PCM in CAF audio description chunk.
+const (
+	cafLinearPCMFormatFlagIsFloat        = 1
+	cafLinearPCMFormatFlagIsLittleEndian = 2
+)
+
+// MarshalCAF returns audio data as Core Audio Format (CAF) data, which consists of the file header,
+// desc chunk and data chunk. The samples are stored in little endian as they are in WAV, and the
+// descriptor tells so. The unsigned 8 bit samples are converted to signed.
+func (v *File) MarshalCAF() ([]byte, error) {
+	flags := uint32(cafLinearPCMFormatFlagIsLittleEndian)
+	if v.SubFormat() == WAVE_FORMAT_IEEE_FLOAT {
+		flags |= cafLinearPCMFormatFlagIsFloat
+	}
+
+	buf := new(bytes.Buffer)
+	buf.Grow(len(v.data) + 68)
+
+	buf.WriteString("caff")
+	binary.Write(buf, binary.BigEndian, uint16(1)) // file version
+	binary.Write(buf, binary.BigEndian, uint16(0)) // file flags
+
+	buf.WriteString("desc")
+	binary.Write(buf, binary.BigEndian, int64(32))
+	binary.Write(buf, binary.BigEndian, math.Float64bits(float64(v.samplesPerSec)))
+	buf.WriteString("lpcm")
+	binary.Write(buf, binary.BigEndian, flags)
+	binary.Write(buf, binary.BigEndian, uint32(v.blockAlign)) // bytes per packet
+	binary.Write(buf, binary.BigEndian, uint32(1))            // frames per packet
+	binary.Write(buf, binary.BigEndian, uint32(v.channels))
+	binary.Write(buf, binary.BigEndian, uint32(v.bitsPerSample))
+
+	// The data chunk starts with 4 bytes of edit count.
+	buf.WriteString("data")
+	binary.Write(buf, binary.BigEndian, int64(4+len(v.data)))
+	binary.Write(buf, binary.BigEndian, uint32(0))
+	if v.BitsPerSample() == 8 {
+		for _, b := range v.data {
+			buf.WriteByte(b ^ 0x80)
+		}
+	} else {
+		buf.Write(v.data)
+	}
+
+	return buf.Bytes(), nil
+}
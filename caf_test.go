@@ -0,0 +1,58 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"testing"
+)
+
+func TestMarshalCAF(t *testing.T) {
+	filenames := []string{
+		"./testdata/sawtooth.wav",
+		"./testdata/44100Hz-32bit-2ch-float.wav",
+		"./testdata/48000Hz-24bit-6ch-5.1.wav",
+	}
+	for _, filename := range filenames {
+		file, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		audio, err := Decode(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		stream, err := audio.MarshalCAF()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(stream[0:4]) != "caff" || string(stream[8:12]) != "desc" || string(stream[28:32]) != "lpcm" {
+			t.Fatalf("CAF header must be written (%v)", filename)
+		}
+
+		desc := stream[20:52]
+		if rate := math.Float64frombits(binary.BigEndian.Uint64(desc[0:8])); rate != float64(audio.SamplesPerSec()) {
+			t.Fatalf("expected: %v actual: %v (%v)", audio.SamplesPerSec(), rate, filename)
+		}
+		if channels := binary.BigEndian.Uint32(desc[24:28]); int(channels) != audio.Channels() {
+			t.Fatalf("expected: %v actual: %v (%v)", audio.Channels(), channels, filename)
+		}
+		if bits := binary.BigEndian.Uint32(desc[28:32]); int(bits) != audio.BitsPerSample() {
+			t.Fatalf("expected: %v actual: %v (%v)", audio.BitsPerSample(), bits, filename)
+		}
+		float := binary.BigEndian.Uint32(desc[12:16])&cafLinearPCMFormatFlagIsFloat != 0
+		if float != (audio.SubFormat() == WAVE_FORMAT_IEEE_FLOAT) {
+			t.Fatalf("expected: %v actual: %v (%v)", !float, float, filename)
+		}
+
+		if string(stream[52:56]) != "data" || binary.BigEndian.Uint64(stream[56:64]) != uint64(4+audio.Length()) {
+			t.Fatalf("data chunk must be written (%v)", filename)
+		}
+		if !bytes.Equal(stream[68:], audio.Bytes()) {
+			t.Fatalf("audio samples must be written (%v)", filename)
+		}
+	}
+	return
+}
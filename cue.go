@@ -0,0 +1,49 @@
+package wav
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// cuePointSize is the size of each cue point which follows the number of the cue points in cue chunk.
+// The cue point consists of ID, Position, DataChunkID, ChunkStart, BlockStart and SampleOffset.
+const cuePointSize = 24
+
+// CuePoints returns the sample offsets of the cue points in "cue " chunk, that is the frame where each marker is placed.
+// It returns nil if the audio has no cue chunk. The labels of the markers are kept in LIST adtl chunk as is.
+func (v *File) CuePoints() []int {
+	if v.cue == nil || len(v.cue.data) < 4 {
+		return nil
+	}
+
+	data := v.cue.data
+	count := int(binary.LittleEndian.Uint32(data[0:4]))
+	if count > (len(data)-4)/cuePointSize {
+		count = (len(data) - 4) / cuePointSize
+	}
+
+	offsets := make([]int, count)
+	for i := range offsets {
+		offsets[i] = int(binary.LittleEndian.Uint32(data[4+i*cuePointSize+20:]))
+	}
+
+	return offsets
+}
+
+// scaleCuePoints multiplies Position and SampleOffset of every cue point by ratio, so that the markers stay
+// at the same time after the sample rate is changed. The chunk may be shared with the other File, so it is copied before modification.
+func (v *File) scaleCuePoints(ratio float64) {
+	if v.cue == nil {
+		return
+	}
+
+	data := make([]byte, len(v.cue.data))
+	copy(data, v.cue.data)
+	for offset := 4; offset+cuePointSize <= len(data); offset += cuePointSize {
+		for _, field := range []int{offset + 4, offset + 20} {
+			scaled := math.Round(float64(binary.LittleEndian.Uint32(data[field:])) * ratio)
+			binary.LittleEndian.PutUint32(data[field:], uint32(math.Min(scaled, math.MaxUint32)))
+		}
+	}
+	v.cue = &rawChunk{id: v.cue.id, data: data}
+}
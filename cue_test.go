@@ -0,0 +1,57 @@
+package wav
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+func TestCuePoints(t *testing.T) {
+	filename := "./testdata/44100Hz-16bit-1ch-cue.wav"
+	file, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, actual := "[50]", fmt.Sprint(audio.CuePoints()); expected != actual {
+		t.Fatalf("expected: %v actual: %v (%v)", expected, actual, filename)
+	}
+	stream, err := Marshal(audio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(file, stream) {
+		t.Fatalf("cue and LIST chunk must be preserved (%v)", filename)
+	}
+
+	streamed, err := DecodeReader(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := "[50]", fmt.Sprint(streamed.CuePoints()); expected != actual {
+		t.Fatalf("expected: %v actual: %v (%v)", expected, actual, filename)
+	}
+
+	// The marker stays at the same time after resampling.
+	resampled, err := audio.Resample(22050)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := "[25]", fmt.Sprint(resampled.CuePoints()); expected != actual {
+		t.Fatalf("expected: %v actual: %v (%v)", expected, actual, filename)
+	}
+	if expected, actual := "[50]", fmt.Sprint(audio.CuePoints()); expected != actual {
+		t.Fatalf("the original cue points must be unchanged: %v", actual)
+	}
+
+	plain, _ := New(44100, 16, 1)
+	if plain.CuePoints() != nil {
+		t.Fatalf("expected: nil actual: %v", plain.CuePoints())
+	}
+	return
+}
@@ -0,0 +1,777 @@
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// SwapChannels exchanges the samples of channel a and channel b in place.
+// For example, SwapChannels(0, 1) swaps left and right channel of the stereo audio.
+func (v *File) SwapChannels(a, b int) error {
+	channels := v.Channels()
+
+	if a < 0 || a >= channels || b < 0 || b >= channels {
+		return fmt.Errorf("wav: invalid channel index (%v, %v)", a, b)
+	}
+	if a == b {
+		return nil
+	}
+	if err := v.checkBlockAlign(); err != nil {
+		return err
+	}
+
+	length := v.Length()
+	stride := v.BlockAlign()
+	width := stride / channels
+
+	for i := 0; i+stride <= length; i += stride {
+		for j := 0; j < width; j++ {
+			v.data[i+a*width+j], v.data[i+b*width+j] = v.data[i+b*width+j], v.data[i+a*width+j]
+		}
+	}
+
+	return nil
+}
+
+// ReorderChannels permutes the channels in place, so that the channel i of the result is the channel order[i] of the original.
+// The order must be a permutation of the channel indices, for example [1 0] swaps left and right channel of the stereo audio.
+// The channel mask is not changed.
+func (v *File) ReorderChannels(order []int) error {
+	channels := v.Channels()
+	if len(order) != channels {
+		return fmt.Errorf("wav: length of order (%v) does not match channels (%v)", len(order), channels)
+	}
+
+	used := make([]bool, channels)
+	for _, c := range order {
+		if c < 0 || c >= channels || used[c] {
+			return fmt.Errorf("wav: order %v is not a permutation of channels", order)
+		}
+		used[c] = true
+	}
+	if err := v.checkBlockAlign(); err != nil {
+		return err
+	}
+
+	length := v.Length()
+	stride := v.BlockAlign()
+	width := stride / channels
+	frame := make([]byte, stride)
+
+	for i := 0; i+stride <= length; i += stride {
+		copy(frame, v.data[i:i+stride])
+		for c, from := range order {
+			copy(v.data[i+c*width:i+(c+1)*width], frame[from*width:(from+1)*width])
+		}
+	}
+
+	return nil
+}
+
+// Pan positions the stereo audio with equal-power panning.
+// The position ranges from -1.0 (hard left) to 1.0 (hard right), and 0.0 keeps the center.
+func (v *File) Pan(position float64) error {
+	if v.Channels() != 2 {
+		return fmt.Errorf("wav: pan requires stereo audio (%v channel(s))", v.Channels())
+	}
+	if position < -1 || position > 1 {
+		return fmt.Errorf("wav: invalid pan position (%v)", position)
+	}
+
+	theta := (position + 1) * math.Pi / 4
+	gains := [2]float64{math.Cos(theta), math.Sin(theta)}
+	f64 := v.Float64s()
+
+	for i := range f64 {
+		f64[i] *= gains[i%2]
+	}
+	v.setFloat64s(f64)
+
+	return nil
+}
+
+// GainChannels multiplies the samples of each channel by the corresponding gain factor.
+// The length of gains must equal the number of channels. The samples out of range are clamped.
+func (v *File) GainChannels(gains []float64) error {
+	channels := v.Channels()
+	if len(gains) != channels {
+		return fmt.Errorf("wav: number of gains (%v) does not match channels (%v)", len(gains), channels)
+	}
+
+	f64 := v.Float64s()
+	for i := range f64 {
+		f64[i] *= gains[i%channels]
+	}
+	v.setFloat64s(f64)
+
+	return nil
+}
+
+// AlignChannels shifts channel b so that it lines up with channel a.
+// The offset is detected by ChannelDelaySamples, and the frames which are shifted in are silent.
+func (v *File) AlignChannels(a, b int) error {
+	channels := v.Channels()
+	if a < 0 || a >= channels || b < 0 || b >= channels {
+		return fmt.Errorf("wav: invalid channel index (%v, %v)", a, b)
+	}
+
+	delay := v.ChannelDelaySamples(a, b)
+	if delay == 0 {
+		return nil
+	}
+
+	frames := v.Frames()
+	f64 := v.Float64s()
+	shifted := make([]float64, frames)
+	for i := range shifted {
+		if j := i + delay; j >= 0 && j < frames {
+			shifted[i] = f64[j*channels+b]
+		}
+	}
+	for i, f := range shifted {
+		f64[i*channels+b] = f
+	}
+	v.setFloat64s(f64)
+
+	return nil
+}
+
+// ToMidSide converts the stereo audio from left / right to mid / side,
+// where mid is (L+R)/2 and side is (L-R)/2. The mid is stored in the first channel.
+func (v *File) ToMidSide() error {
+	return v.mapStereo(func(l, r float64) (float64, float64) {
+		return (l + r) / 2, (l - r) / 2
+	})
+}
+
+// FromMidSide converts the stereo audio from mid / side back to left / right.
+// The samples out of range are clamped.
+func (v *File) FromMidSide() error {
+	return v.mapStereo(func(m, s float64) (float64, float64) {
+		return m + s, m - s
+	})
+}
+
+// mapStereo replaces each pair of the stereo samples with the result of fn.
+func (v *File) mapStereo(fn func(a, b float64) (float64, float64)) error {
+	if v.Channels() != 2 {
+		return fmt.Errorf("wav: stereo audio is required (%v channel(s))", v.Channels())
+	}
+
+	f64 := v.Float64s()
+	for i := 0; i+1 < len(f64); i += 2 {
+		f64[i], f64[i+1] = fn(f64[i], f64[i+1])
+	}
+	v.setFloat64s(f64)
+
+	return nil
+}
+
+// ChangeSpeed returns a new File whose playback speed is multiplied by factor.
+// The factor greater than 1.0 shortens the audio and less than 1.0 lengthens it.
+// Note that the audio is resampled naively at the same sample rate, so the pitch changes as well.
+func (v *File) ChangeSpeed(factor float64) (*File, error) {
+	if factor <= 0 || math.IsInf(factor, 0) || math.IsNaN(factor) {
+		return nil, fmt.Errorf("wav: invalid speed factor (%v)", factor)
+	}
+
+	return v.interpolate(factor), nil
+}
+
+// interpolate returns a new File whose frames are linearly interpolated at every factor frames of v.
+func (v *File) interpolate(factor float64) *File {
+	channels := v.Channels()
+	frames := v.Frames()
+	src := v.Float64s()
+	n := int(math.Round(float64(frames) / factor))
+	dst := make([]float64, n*channels)
+
+	for i := 0; i < n; i++ {
+		pos := float64(i) * factor
+		j := int(pos)
+		frac := pos - float64(j)
+
+		for c := 0; c < channels; c++ {
+			a := src[j*channels+c]
+			b := a
+			if j+1 < frames {
+				b = src[(j+1)*channels+c]
+			}
+			dst[i*channels+c] = a + (b-a)*frac
+		}
+	}
+
+	audio := v.empty()
+	audio.setFloat64s(dst)
+
+	return audio
+}
+
+// Resample returns a new File which is resampled to rate samples per second by linear interpolation.
+// The cue points are rescaled so that the markers stay at the same time.
+func (v *File) Resample(rate int) (*File, error) {
+	if rate <= 0 {
+		return nil, fmt.Errorf("wav: invalid sample rate (%v)", rate)
+	}
+
+	audio := v.interpolate(float64(v.SamplesPerSec()) / float64(rate))
+	audio.cue = v.cue
+	audio.scaleCuePoints(float64(rate) / float64(v.SamplesPerSec()))
+	audio.SetSampleRate(rate)
+
+	return audio, nil
+}
+
+// ResampleSinc returns a new File which is resampled to rate samples per second by Hann windowed sinc interpolation.
+// Each output sample is computed from taps input samples per channel, the larger taps gives the sharper low-pass filter.
+// When downsampling, the cutoff frequency is lowered to the new Nyquist frequency to suppress aliasing.
+// The cue points are rescaled in the same way as Resample.
+func (v *File) ResampleSinc(rate int, taps int) (*File, error) {
+	if rate <= 0 {
+		return nil, fmt.Errorf("wav: invalid sample rate (%v)", rate)
+	}
+	if taps < 2 {
+		return nil, fmt.Errorf("wav: invalid number of taps (%v)", taps)
+	}
+
+	factor := float64(v.SamplesPerSec()) / float64(rate)
+	cutoff := math.Min(1, 1/factor)
+	half := float64(taps) / 2
+
+	kernel := func(x float64) float64 {
+		if math.Abs(x) >= half {
+			return 0
+		}
+		window := 0.5 * (1 + math.Cos(math.Pi*x/half))
+		if x == 0 {
+			return cutoff * window
+		}
+		return cutoff * math.Sin(math.Pi*x) / (math.Pi * x) * window
+	}
+
+	channels := v.Channels()
+	frames := v.Frames()
+	src := v.Float64s()
+	n := int(math.Round(float64(frames) / factor))
+	dst := make([]float64, n*channels)
+
+	// The filter is stretched by 1/cutoff when downsampling.
+	width := half / cutoff
+	for i := 0; i < n; i++ {
+		pos := float64(i) * factor
+		first := int(math.Ceil(pos - width))
+		last := int(math.Floor(pos + width))
+		if first < 0 {
+			first = 0
+		}
+		if last >= frames {
+			last = frames - 1
+		}
+
+		for j := first; j <= last; j++ {
+			w := kernel((pos - float64(j)) * cutoff)
+			if w == 0 {
+				continue
+			}
+			for c := 0; c < channels; c++ {
+				dst[i*channels+c] += src[j*channels+c] * w
+			}
+		}
+	}
+
+	audio := v.empty()
+	audio.setFloat64s(dst)
+	audio.cue = v.cue
+	audio.scaleCuePoints(float64(rate) / float64(v.SamplesPerSec()))
+	audio.SetSampleRate(rate)
+
+	return audio, nil
+}
+
+// ResampleToStandard returns a new File which is resampled to the standard rate of family,
+// either 44100 (CD) or 48000 (video and broadcast).
+func (v *File) ResampleToStandard(family int) (*File, error) {
+	if !(family == 44100 || family == 48000) {
+		return nil, fmt.Errorf("wav: unknown sample rate family (%v)", family)
+	}
+	return v.Resample(family)
+}
+
+// ClampSampleRate returns a new File whose sample rate is within min to max.
+// The audio is resampled up to min or down to max only when its rate is out of the range,
+// otherwise the copy of the audio is returned.
+func (v *File) ClampSampleRate(min, max int) (*File, error) {
+	if min <= 0 || max < min {
+		return nil, fmt.Errorf("wav: invalid sample rate range (%v to %v)", min, max)
+	}
+
+	rate := v.SamplesPerSec()
+	if rate < min {
+		return v.Resample(min)
+	}
+	if rate > max {
+		return v.Resample(max)
+	}
+
+	audio := v.empty()
+	audio.Write(v.data)
+
+	return audio, nil
+}
+
+// Split splits the audio into chunks of duration d on frame boundaries.
+// The last chunk may be shorter than d. If d is longer than the audio, the result contains only one chunk.
+func (v *File) Split(d time.Duration) ([]*File, error) {
+	size := v.framesOf(d) * v.BlockAlign()
+	if size <= 0 {
+		return nil, fmt.Errorf("wav: invalid split duration (%v)", d)
+	}
+
+	length := len(v.data)
+	chunks := []*File{}
+
+	for offset := 0; offset < length || offset == 0; offset += size {
+		end := offset + size
+		if end > length {
+			end = length
+		}
+
+		chunk := v.empty()
+		chunk.Write(v.data[offset:end])
+		chunk.shiftTimeReference(offset / v.BlockAlign())
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks, nil
+}
+
+// Channel returns a new mono File which contains the samples of channel c.
+// The sample format, such as bit depth and IEEE float, is carried over.
+func (v *File) Channel(c int) (*File, error) {
+	channels := v.Channels()
+	if c < 0 || c >= channels {
+		return nil, fmt.Errorf("wav: channel %v is out of range (%v channels)", c, channels)
+	}
+
+	audio := v.empty()
+	audio.channels = 1
+	audio.channelMask = 0
+	audio.extension = nil
+	audio.blockAlign = v.bitsPerSample / 8
+	audio.avgBytesPerSec = v.samplesPerSec * uint32(audio.blockAlign)
+
+	width := int(audio.blockAlign)
+	stride := v.BlockAlign()
+	// The data may be shorter than the declared length when the file is truncated.
+	frames := len(v.data) / stride
+	data := make([]byte, frames*width)
+	for i := 0; i < frames; i++ {
+		copy(data[i*width:(i+1)*width], v.data[i*stride+c*width:])
+	}
+	audio.Write(data)
+
+	return audio, nil
+}
+
+// ExpandChannels returns a new File which has target channels, where the channel c of the audio is placed at placement[c]
+// and the other channels are silent. For example, ExpandChannels(6, []int{2}) places the mono audio at the front center
+// of 5.1 surround audio. The channel mask of the result is the conventional mask for target channels.
+func (v *File) ExpandChannels(target int, placement []int) (*File, error) {
+	channels := v.Channels()
+	if len(placement) != channels {
+		return nil, fmt.Errorf("wav: length of placement (%v) does not match channels (%v)", len(placement), channels)
+	}
+	if target < channels || target > math.MaxUint16 {
+		return nil, fmt.Errorf("wav: invalid target channels (%v)", target)
+	}
+
+	used := make([]bool, target)
+	for _, c := range placement {
+		if c < 0 || c >= target || used[c] {
+			return nil, fmt.Errorf("wav: invalid placement %v for %v channels", placement, target)
+		}
+		used[c] = true
+	}
+
+	audio := v.empty()
+	audio.channels = uint16(target)
+	audio.channelMask = 0
+	audio.extension = nil
+	audio.blockAlign = audio.channels * v.bitsPerSample / 8
+	audio.avgBytesPerSec = v.samplesPerSec * uint32(audio.blockAlign)
+
+	width := int(v.bitsPerSample) / 8
+	stride := v.BlockAlign()
+	expanded := audio.BlockAlign()
+	frames := v.Frames()
+	data := make([]byte, frames*expanded)
+
+	// The silence of the unsigned 8 bit audio is 128.
+	if v.BitsPerSample() == 8 {
+		for i := range data {
+			data[i] = 128
+		}
+	}
+	for i := 0; i < frames; i++ {
+		for c, to := range placement {
+			copy(data[i*expanded+to*width:i*expanded+(to+1)*width], v.data[i*stride+c*width:])
+		}
+	}
+	audio.Write(data)
+
+	return audio, nil
+}
+
+// SplitChannels returns the mono Files, one per channel.
+// The mono audio returns a slice which contains only one File.
+func (v *File) SplitChannels() ([]*File, error) {
+	files := make([]*File, v.Channels())
+	for c := range files {
+		file, err := v.Channel(c)
+		if err != nil {
+			return nil, err
+		}
+		files[c] = file
+	}
+
+	return files, nil
+}
+
+// SplitOnSilence splits the audio wherever the frames below threshold last minSilence or longer,
+// and returns the non-silent segments. The threshold is compared with the normalized absolute amplitude
+// of the loudest channel in each frame. The leading and trailing silence is dropped.
+func (v *File) SplitOnSilence(threshold float64, minSilence time.Duration) ([]*File, error) {
+	minFrames := v.framesOf(minSilence)
+	if minFrames <= 0 {
+		return nil, fmt.Errorf("wav: invalid minimum silence (%v)", minSilence)
+	}
+
+	channels := v.Channels()
+	stride := v.BlockAlign()
+	frames := v.Frames()
+	f64 := v.Float64s()
+	segments := []*File{}
+
+	cut := func(start, end int) {
+		segment := v.empty()
+		segment.Write(v.data[start*stride : end*stride])
+		segment.shiftTimeReference(start)
+		segments = append(segments, segment)
+	}
+
+	start, lastLoud := -1, -1
+	for i := 0; i < frames; i++ {
+		loud := false
+		for c := 0; c < channels; c++ {
+			if math.Abs(f64[i*channels+c]) >= threshold {
+				loud = true
+				break
+			}
+		}
+		if !loud {
+			continue
+		}
+		if start < 0 {
+			start = i
+		} else if i-lastLoud-1 >= minFrames {
+			cut(start, lastLoud+1)
+			start = i
+		}
+		lastLoud = i
+	}
+	if start >= 0 {
+		cut(start, lastLoud+1)
+	}
+
+	return segments, nil
+}
+
+// ConvertBitDepth returns a new File whose samples are converted to bits per sample.
+// The format properties other than bit depth and the metadata chunks, such as the channel mask, bext, cue, LIST and id3 chunk,
+// are carried over. The cue points are kept as is, since the sample rate does not change. Resample and ResampleSinc rescale
+// the cue points instead. The samples are always encoded as integer PCM.
+func (v *File) ConvertBitDepth(bits int) (*File, error) {
+	if !(bits == 8 || bits == 16 || bits == 24 || bits == 32) {
+		return nil, fmt.Errorf("wav: invalid bits per sample (%v bit)", bits)
+	}
+
+	audio := v.empty()
+	// Choose the format tag in the same way as New.
+	if bits > 16 {
+		audio.formatTag = WAVE_FORMAT_EXTENSIBLE
+	} else {
+		audio.formatTag = WAVE_FORMAT_PCM
+	}
+	audio.subFormat = WAVE_FORMAT_PCM
+	// The extension holds the valid bits per sample of the original, so that it is rebuilt by Marshal.
+	audio.extension = nil
+	audio.bitsPerSample = uint16(bits)
+	audio.blockAlign = audio.channels * audio.bitsPerSample / 8
+	audio.avgBytesPerSec = audio.samplesPerSec * uint32(audio.blockAlign)
+	audio.cue = v.cue
+	audio.setFloat64s(v.Float64s())
+
+	return audio, nil
+}
+
+// LoudestRegion returns a new File which contains the region of duration d with the highest RMS.
+// The region starts on a frame boundary. If the audio is shorter than d, the whole audio is returned.
+func (v *File) LoudestRegion(d time.Duration) (*File, error) {
+	size := v.framesOf(d)
+	if size <= 0 {
+		return nil, fmt.Errorf("wav: invalid region duration (%v)", d)
+	}
+
+	frames := v.Frames()
+	if size > frames {
+		size = frames
+	}
+
+	channels := v.Channels()
+	f64 := v.Float64s()
+
+	// energy[i] is the sum of squared samples of the first i frames.
+	energy := make([]float64, frames+1)
+	for i := 0; i < frames; i++ {
+		sum := 0.0
+		for c := 0; c < channels; c++ {
+			sum += f64[i*channels+c] * f64[i*channels+c]
+		}
+		energy[i+1] = energy[i] + sum
+	}
+
+	best := 0
+	for start := 1; start+size <= frames; start++ {
+		if energy[start+size]-energy[start] > energy[best+size]-energy[best] {
+			best = start
+		}
+	}
+
+	stride := v.BlockAlign()
+	region := v.empty()
+	region.Write(v.data[best*stride : (best+size)*stride])
+	region.shiftTimeReference(best)
+
+	return region, nil
+}
+
+// frameRange returns the frames between start and end snapped to frame boundaries.
+func (v *File) frameRange(start, end time.Duration) (int, int, error) {
+	first := v.framesOf(start)
+	last := v.framesOf(end)
+
+	// Check against the actual data rather than Frames, the file may be truncated.
+	frames := 0
+	if v.BlockAlign() > 0 {
+		frames = len(v.data) / v.BlockAlign()
+	}
+	if start < 0 || end < start || last > frames {
+		return 0, 0, fmt.Errorf("wav: invalid range (%v - %v)", start, end)
+	}
+
+	return first, last, nil
+}
+
+// Mute silences the audio between start and end.
+func (v *File) Mute(start, end time.Duration) error {
+	first, last, err := v.frameRange(start, end)
+	if err != nil {
+		return err
+	}
+
+	var silence byte
+	if v.BitsPerSample() == 8 {
+		silence = 0x80
+	}
+
+	stride := v.BlockAlign()
+	region := v.data[first*stride : last*stride]
+	for i := range region {
+		region[i] = silence
+	}
+
+	return nil
+}
+
+// Bleep replaces the audio between start and end with the sine tone of freq Hz at -12 dBFS on every channel.
+func (v *File) Bleep(start, end time.Duration, freq float64) error {
+	const amplitude = 0.25
+
+	if freq <= 0 || math.IsInf(freq, 0) || math.IsNaN(freq) {
+		return fmt.Errorf("wav: invalid frequency (%v Hz)", freq)
+	}
+
+	first, last, err := v.frameRange(start, end)
+	if err != nil {
+		return err
+	}
+
+	channels := v.Channels()
+	f64 := v.Float64s()
+	for i := first; i < last; i++ {
+		f := amplitude * math.Sin(2*math.Pi*freq*float64(i-first)/float64(v.SamplesPerSec()))
+		for c := 0; c < channels; c++ {
+			f64[i*channels+c] = f
+		}
+	}
+	v.setFloat64s(f64)
+
+	return nil
+}
+
+// compatible returns an error if other has different format from v.
+func (v *File) compatible(other *File) error {
+	if other == nil {
+		return fmt.Errorf("wav: nil audio")
+	}
+	if v.SubFormat() != other.SubFormat() ||
+		v.channels != other.channels ||
+		v.samplesPerSec != other.samplesPerSec ||
+		v.bitsPerSample != other.bitsPerSample {
+		return fmt.Errorf("wav: incompatible format (%v and %v)", v, other)
+	}
+	return nil
+}
+
+// Prepend inserts the audio samples of other before the audio samples of v.
+// Both audio must have the same format.
+func (v *File) Prepend(other *File) error {
+	if err := v.compatible(other); err != nil {
+		return err
+	}
+
+	data := make([]byte, 0, len(other.data)+len(v.data))
+	data = append(data, other.data...)
+	data = append(data, v.data...)
+	v.data = data
+	v.length += other.length
+
+	return nil
+}
+
+// Insert inserts the audio samples of other at the frame nearest to at.
+// If at is beyond the end of v, other is appended. Both audio must have the same format.
+func (v *File) Insert(at time.Duration, other *File) error {
+	if at < 0 {
+		return fmt.Errorf("wav: invalid position (%v)", at)
+	}
+	if err := v.compatible(other); err != nil {
+		return err
+	}
+
+	frame := v.framesOf(at)
+	if frame > v.Frames() {
+		frame = v.Frames()
+	}
+	offset := frame * v.BlockAlign()
+
+	data := make([]byte, 0, len(v.data)+len(other.data))
+	data = append(data, v.data[:offset]...)
+	data = append(data, other.data...)
+	data = append(data, v.data[offset:]...)
+	v.data = data
+	v.length += other.length
+
+	return nil
+}
+
+// Delete removes the audio between start and end.
+func (v *File) Delete(start, end time.Duration) error {
+	first, last, err := v.frameRange(start, end)
+	if err != nil {
+		return err
+	}
+
+	stride := v.BlockAlign()
+	data := make([]byte, 0, len(v.data)-(last-first)*stride)
+	data = append(data, v.data[:first*stride]...)
+	data = append(data, v.data[last*stride:]...)
+	v.data = data
+	v.length -= uint32((last - first) * stride)
+
+	if first == 0 {
+		v.shiftTimeReference(last)
+	}
+
+	return nil
+}
+
+// Map replaces each sample with the result of fn.
+// The sample passed to fn is normalized in the same way as Float64s,
+// and the result is written back at the native bit depth with clamping.
+func (v *File) Map(fn func(sample float64) float64) {
+	f64 := v.Float64s()
+	for i, f := range f64 {
+		f64[i] = fn(f)
+	}
+	v.setFloat64s(f64)
+}
+
+// MapFrames replaces each frame with the result of fn.
+// The frame passed to fn holds one normalized sample per channel,
+// and fn must return a frame which has the same number of samples.
+func (v *File) MapFrames(fn func(frame []float64) []float64) error {
+	channels := v.Channels()
+	frames := v.Frames()
+	f64 := v.Float64s()
+
+	for i := 0; i < frames; i++ {
+		frame := make([]float64, channels)
+		copy(frame, f64[i*channels:(i+1)*channels])
+
+		result := fn(frame)
+		if len(result) != channels {
+			return fmt.Errorf("wav: frame %v has %v samples but audio has %v channel(s)", i, len(result), channels)
+		}
+		copy(f64[i*channels:], result)
+	}
+	v.setFloat64s(f64)
+
+	return nil
+}
+
+// SwapSampleBytes reverses the byte order of every sample in place.
+// It fixes the audio whose samples are stored in big endian despite RIFF header.
+func (v *File) SwapSampleBytes() {
+	width := v.BitsPerSample() / 8
+	if width < 2 {
+		return
+	}
+
+	for i := 0; i+width <= len(v.data); i += width {
+		sample := v.data[i : i+width]
+		for a, b := 0, width-1; a < b; a, b = a+1, b-1 {
+			sample[a], sample[b] = sample[b], sample[a]
+		}
+	}
+}
+
+// DetectEndianness guesses the byte order of the samples.
+// Natural audio changes smoothly between adjacent frames, so the byte order which gives
+// the smaller difference between adjacent samples is returned. 8 bit audio is always little endian.
+func (v *File) DetectEndianness() binary.ByteOrder {
+	if v.BitsPerSample() < 16 {
+		return binary.LittleEndian
+	}
+
+	roughness := func(audio *File) float64 {
+		channels := audio.Channels()
+		f64 := audio.Float64s()
+		sum := 0.0
+		for i := channels; i < len(f64); i++ {
+			sum += math.Abs(f64[i] - f64[i-channels])
+		}
+		return sum
+	}
+
+	swapped := v.empty()
+	swapped.Write(v.data)
+	swapped.SwapSampleBytes()
+
+	if roughness(swapped) < roughness(v) {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
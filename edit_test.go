@@ -0,0 +1,955 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// newTestFile creates 16 bit audio which contains the given frames.
+// fn returns the amplitude (-1.0 to 1.0) of the channel c at the frame i.
+func newTestFile(t *testing.T, samplesPerSec, channels, frames int, fn func(i, c int) float64) *File {
+	audio, err := New(samplesPerSec, 16, channels)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	for i := 0; i < frames; i++ {
+		for c := 0; c < channels; c++ {
+			binary.Write(buf, binary.LittleEndian, int16(fn(i, c)*math.MaxInt16))
+		}
+	}
+	audio.Write(buf.Bytes())
+
+	return audio
+}
+
+func sine(freq float64, samplesPerSec int) func(i, c int) float64 {
+	return func(i, c int) float64 {
+		return 0.5 * math.Sin(2*math.Pi*freq*float64(i)/float64(samplesPerSec))
+	}
+}
+
+func TestSwapChannels(t *testing.T) {
+	left := sine(440, 44100)
+	right := sine(1000, 44100)
+	audio := newTestFile(t, 44100, 2, 4410, func(i, c int) float64 {
+		if c == 0 {
+			return left(i, c)
+		}
+		return right(i, c)
+	})
+
+	if err := audio.SwapChannels(0, 2); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err := audio.SwapChannels(0, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	s32 := audio.Int32s()
+	for i := 0; i < 4410; i++ {
+		expectedLeft := int32(int16(right(i, 1)*math.MaxInt16)) << 16
+		expectedRight := int32(int16(left(i, 0)*math.MaxInt16)) << 16
+
+		if s32[i*2] != expectedLeft {
+			t.Fatalf("[%v] expected: %v actual: %v", i, expectedLeft, s32[i*2])
+		}
+		if s32[i*2+1] != expectedRight {
+			t.Fatalf("[%v] expected: %v actual: %v", i, expectedRight, s32[i*2+1])
+		}
+	}
+
+	file, err := ioutil.ReadFile("./testdata/44100Hz-16bit-2ch-zeroalign.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = zero.SwapChannels(0, 1); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
+
+func TestReorderChannels(t *testing.T) {
+	left := sine(440, 44100)
+	right := sine(1000, 44100)
+	fn := func(i, c int) float64 {
+		if c == 0 {
+			return left(i, c)
+		}
+		return right(i, c)
+	}
+	audio := newTestFile(t, 44100, 2, 4410, fn)
+	expected := newTestFile(t, 44100, 2, 4410, fn)
+
+	if err := audio.ReorderChannels([]int{1, 1}); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err := audio.ReorderChannels([]int{0}); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err := audio.ReorderChannels([]int{1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := expected.SwapChannels(0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected.Bytes(), audio.Bytes()) {
+		t.Fatalf("reordered audio must be same as swapped audio")
+	}
+
+	surround := newTestFile(t, 8000, 3, 10, func(i, c int) float64 { return float64(c) / 4 })
+	if err := surround.ReorderChannels([]int{2, 0, 1}); err != nil {
+		t.Fatal(err)
+	}
+	s16 := surround.Int16s()
+	if s16[0] != 16383 || s16[1] != 0 || s16[2] != 8191 {
+		t.Fatalf("expected: [16383 0 8191] actual: %v", s16[:3])
+	}
+
+	file, err := ioutil.ReadFile("./testdata/44100Hz-16bit-2ch-zeroalign.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = zero.ReorderChannels([]int{1, 0}); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
+
+func TestPan(t *testing.T) {
+	audio := newTestFile(t, 44100, 2, 4410, sine(440, 44100))
+
+	if err := audio.Pan(-1); err != nil {
+		t.Fatal(err)
+	}
+
+	f64 := audio.Float64s()
+	for i := 0; i < 4410; i++ {
+		if f64[i*2+1] != 0 {
+			t.Fatalf("[%v] expected: 0 actual: %v", i, f64[i*2+1])
+		}
+	}
+
+	mono := newTestFile(t, 44100, 1, 4410, sine(440, 44100))
+	if err := mono.Pan(0); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
+
+func TestGainChannels(t *testing.T) {
+	audio := newTestFile(t, 44100, 2, 4410, sine(440, 44100))
+	expected := audio.Float64s()
+
+	if err := audio.GainChannels([]float64{1.0, 0.5}); err != nil {
+		t.Fatal(err)
+	}
+
+	actual := audio.Float64s()
+	for i := 0; i < 4410; i++ {
+		if actual[i*2] != expected[i*2] {
+			t.Fatalf("[%v] expected: %v actual: %v", i, expected[i*2], actual[i*2])
+		}
+		if math.Abs(actual[i*2+1]-expected[i*2+1]*0.5) > 1e-4 {
+			t.Fatalf("[%v] expected: %v actual: %v", i, expected[i*2+1]*0.5, actual[i*2+1])
+		}
+	}
+
+	if err := audio.GainChannels([]float64{1.0}); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
+
+func TestChangeSpeed(t *testing.T) {
+	audio := newTestFile(t, 44100, 2, 44100, sine(440, 44100))
+
+	if _, err := audio.ChangeSpeed(0); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+
+	faster, err := audio.ChangeSpeed(2.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if faster.SamplesPerSec() != audio.SamplesPerSec() {
+		t.Fatalf("expected: %v actual: %v", audio.SamplesPerSec(), faster.SamplesPerSec())
+	}
+	if expected, actual := audio.Frames()/2, faster.Frames(); expected != actual {
+		t.Fatalf("expected: %v actual: %v", expected, actual)
+	}
+	return
+}
+
+func TestSplit(t *testing.T) {
+	audio := newTestFile(t, 8000, 2, 80000, sine(440, 8000))
+
+	if _, err := audio.Split(0); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+
+	chunks, err := audio.Split(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 10 {
+		t.Fatalf("expected: 10 actual: %v", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if chunk.Frames() != 8000 {
+			t.Fatalf("[%v] expected: 8000 actual: %v", i, chunk.Frames())
+		}
+		if chunk.Channels() != 2 || chunk.BitsPerSample() != 16 {
+			t.Fatalf("[%v] format must be preserved: %v", i, chunk)
+		}
+	}
+
+	chunks, err = audio.Split(3 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 4 || chunks[3].Frames() != 8000 {
+		t.Fatalf("expected: 4 chunks and the last one has 8000 frames")
+	}
+
+	chunks, err = audio.Split(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 1 || chunks[0].Length() != audio.Length() {
+		t.Fatalf("expected: the whole audio as one chunk")
+	}
+	return
+}
+
+func TestSplitOnSilence(t *testing.T) {
+	tone := sine(440, 8000)
+	audio := newTestFile(t, 8000, 1, 8000*5, func(i, c int) float64 {
+		// silence, tone, silence, tone, silence (1 second each)
+		if (i/8000)%2 == 1 {
+			return tone(i, c)
+		}
+		return 0
+	})
+
+	if _, err := audio.SplitOnSilence(0.01, 0); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+
+	segments, err := audio.SplitOnSilence(0.01, 500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 2 {
+		t.Fatalf("expected: 2 actual: %v", len(segments))
+	}
+	for i, segment := range segments {
+		if segment.Frames() < 7900 || segment.Frames() > 8000 {
+			t.Fatalf("[%v] expected: about 8000 frames actual: %v", i, segment.Frames())
+		}
+	}
+	return
+}
+
+func TestSplitChannels(t *testing.T) {
+	filename := "./testdata/44100Hz-32bit-2ch-float.wav"
+	file, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	channels, err := audio.SplitChannels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channels) != 2 {
+		t.Fatalf("expected: 2 actual: %v", len(channels))
+	}
+	for c, channel := range channels {
+		if channel.Channels() != 1 || channel.Frames() != audio.Frames() || channel.SubFormat() != WAVE_FORMAT_IEEE_FLOAT {
+			t.Fatalf("[%v] expected: mono %v frames actual: %v", c, audio.Frames(), channel)
+		}
+	}
+
+	// Interleave the channels again.
+	width := audio.BlockAlign() / 2
+	recombined := []byte{}
+	for i := 0; i < audio.Frames(); i++ {
+		for _, channel := range channels {
+			recombined = append(recombined, channel.Bytes()[i*width:(i+1)*width]...)
+		}
+	}
+	if !bytes.Equal(audio.Bytes(), recombined) {
+		t.Fatalf("recombined data must be identical to %v", filename)
+	}
+
+	mono := newTestFile(t, 8000, 1, 100, sine(440, 8000))
+	if channels, _ = mono.SplitChannels(); len(channels) != 1 || !bytes.Equal(mono.Bytes(), channels[0].Bytes()) {
+		t.Fatalf("mono audio must be split into itself")
+	}
+	if _, err = audio.Channel(2); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+
+	// The truncated file has 10 frames although it declares 50 frames.
+	if file, err = ioutil.ReadFile("./testdata/44100Hz-16bit-1ch-truncated.wav"); err != nil {
+		t.Fatal(err)
+	}
+	if audio, err = Decode(file); err != nil {
+		t.Fatal(err)
+	}
+	if channels, err = audio.SplitChannels(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(audio.Bytes(), channels[0].Bytes()) {
+		t.Fatalf("expected: %v actual: %v", audio.Bytes(), channels[0].Bytes())
+	}
+	return
+}
+
+func TestAlignChannels(t *testing.T) {
+	noise := rand.New(rand.NewSource(1))
+	mic := make([]float64, 2000)
+	for i := range mic {
+		mic[i] = 0.5 * (noise.Float64()*2 - 1)
+	}
+	// The right channel is the copy of the left channel delayed by 23 frames.
+	audio := newTestFile(t, 8000, 2, 2000, func(i, c int) float64 {
+		if c == 1 {
+			i -= 23
+		}
+		if i < 0 {
+			return 0
+		}
+		return mic[i]
+	})
+
+	if delay := audio.ChannelDelaySamples(0, 1); delay != 23 {
+		t.Fatalf("expected: 23 actual: %v", delay)
+	}
+	if delay := audio.ChannelDelaySamples(1, 0); delay != -23 {
+		t.Fatalf("expected: -23 actual: %v", delay)
+	}
+
+	if err := audio.AlignChannels(0, 2); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err := audio.AlignChannels(0, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	f64 := audio.Float64s()
+	for i := 0; i < 2000-23; i++ {
+		if f64[i*2] != f64[i*2+1] {
+			t.Fatalf("[%v] expected: %v actual: %v", i, f64[i*2], f64[i*2+1])
+		}
+	}
+	if delay := audio.ChannelDelaySamples(0, 1); delay != 0 {
+		t.Fatalf("expected: 0 actual: %v", delay)
+	}
+	return
+}
+
+func TestExpandChannels(t *testing.T) {
+	audio := newTestFile(t, 48000, 1, 4800, sine(440, 48000))
+
+	if _, err := audio.ExpandChannels(6, []int{2, 3}); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if _, err := audio.ExpandChannels(6, []int{6}); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+
+	surround, err := audio.ExpandChannels(6, []int{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if surround.Channels() != 6 || surround.Frames() != audio.Frames() || surround.BlockAlign() != 12 {
+		t.Fatalf("expected: 6 channels %v frames actual: %v", audio.Frames(), surround)
+	}
+	if order := surround.ChannelOrder(); order[2] != "FC" {
+		t.Fatalf("expected: FC actual: %v", order[2])
+	}
+
+	expected := audio.Int16s()
+	s16 := surround.Int16s()
+	for i := range expected {
+		for c := 0; c < 6; c++ {
+			if c == 2 && s16[i*6+c] != expected[i] {
+				t.Fatalf("[%v] expected: %v actual: %v", i, expected[i], s16[i*6+c])
+			}
+			if c != 2 && s16[i*6+c] != 0 {
+				t.Fatalf("[%v] channel %v must be silent", i, c)
+			}
+		}
+	}
+	return
+}
+
+func TestToMidSide(t *testing.T) {
+	left := sine(440, 44100)
+	right := sine(660, 44100)
+	fn := func(i, c int) float64 {
+		if c == 0 {
+			return left(i, c)
+		}
+		return right(i, c)
+	}
+	audio := newTestFile(t, 44100, 2, 4410, fn)
+	original := newTestFile(t, 44100, 2, 4410, fn)
+
+	if err := audio.ToMidSide(); err != nil {
+		t.Fatal(err)
+	}
+	if audio.CloseTo(original, 1e-3) {
+		t.Fatalf("mid / side must differ from left / right")
+	}
+	if err := audio.FromMidSide(); err != nil {
+		t.Fatal(err)
+	}
+	if !audio.CloseTo(original, 1e-4) {
+		t.Fatalf("round trip must reproduce the original")
+	}
+
+	mono := newTestFile(t, 44100, 1, 4410, sine(440, 44100))
+	if err := mono.ToMidSide(); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err := mono.FromMidSide(); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
+
+func TestConvertBitDepth(t *testing.T) {
+	file, err := ioutil.ReadFile("./testdata/48000Hz-24bit-6ch-5.1.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio := &File{}
+	if err = Unmarshal(file, audio); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = audio.ConvertBitDepth(12); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+
+	converted, err := audio.ConvertBitDepth(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if converted.BitsPerSample() != 32 {
+		t.Fatalf("expected: 32 actual: %v", converted.BitsPerSample())
+	}
+	if converted.ChannelMask() != audio.ChannelMask() {
+		t.Fatalf("expected: %#x actual: %#x", audio.ChannelMask(), converted.ChannelMask())
+	}
+	if expected, actual := fmt.Sprint(audio.Int32s()), fmt.Sprint(converted.Int32s()); expected != actual {
+		t.Fatalf("expected: %v actual: %v", expected, actual)
+	}
+
+	// bext and id3 chunk are carried over.
+	if file, err = ioutil.ReadFile("./testdata/44100Hz-16bit-1ch-bext.wav"); err != nil {
+		t.Fatal(err)
+	}
+	if audio, err = Decode(file); err != nil {
+		t.Fatal(err)
+	}
+	if converted, err = audio.ConvertBitDepth(24); err != nil {
+		t.Fatal(err)
+	}
+	if reference, ok := converted.TimeReference(); !ok || reference != 158760000 {
+		t.Fatalf("expected: %v actual: %v", 158760000, reference)
+	}
+	if converted.FormatTag() != WAVE_FORMAT_EXTENSIBLE || converted.BlockAlign() != 3 {
+		t.Fatalf("expected: 24 bit extensible actual: %v", converted)
+	}
+	if file, err = ioutil.ReadFile("./testdata/44100Hz-16bit-1ch-id3.wav"); err != nil {
+		t.Fatal(err)
+	}
+	if audio, err = Decode(file); err != nil {
+		t.Fatal(err)
+	}
+	if converted, err = audio.ConvertBitDepth(8); err != nil {
+		t.Fatal(err)
+	}
+	if tags := converted.ID3(); tags == nil || tags.Artist != "moutend" {
+		t.Fatalf("expected: %v actual: %v", "moutend", tags)
+	}
+
+	// The marker and its label survive the round trip through the conversion.
+	if file, err = ioutil.ReadFile("./testdata/44100Hz-16bit-1ch-cue.wav"); err != nil {
+		t.Fatal(err)
+	}
+	if audio, err = Decode(file); err != nil {
+		t.Fatal(err)
+	}
+	if converted, err = audio.ConvertBitDepth(24); err != nil {
+		t.Fatal(err)
+	}
+	stream, err := Marshal(converted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if converted, err = Decode(stream); err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := "[50]", fmt.Sprint(converted.CuePoints()); expected != actual {
+		t.Fatalf("expected: %v actual: %v", expected, actual)
+	}
+	if !bytes.Contains(stream, []byte("Marker")) {
+		t.Fatalf("LIST chunk must be preserved")
+	}
+	return
+}
+
+func TestLoudestRegion(t *testing.T) {
+	tone := sine(440, 8000)
+	audio := newTestFile(t, 8000, 2, 8000*10, func(i, c int) float64 {
+		// The loud region lasts from 6 to 7 seconds.
+		if i >= 8000*6 && i < 8000*7 {
+			return tone(i, c) * 1.8
+		}
+		return tone(i, c) * 0.1
+	})
+
+	if _, err := audio.LoudestRegion(0); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+
+	region, err := audio.LoudestRegion(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if region.Frames() != 8000 {
+		t.Fatalf("expected: 8000 actual: %v", region.Frames())
+	}
+	offset := bytes.Index(audio.Bytes(), region.Bytes()) / audio.BlockAlign()
+	if offset < 8000*6-50 || offset > 8000*6+50 {
+		t.Fatalf("expected: about %v actual: %v", 8000*6, offset)
+	}
+
+	whole, err := audio.LoudestRegion(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if whole.Length() != audio.Length() {
+		t.Fatalf("expected: %v actual: %v", audio.Length(), whole.Length())
+	}
+	return
+}
+
+func TestMute(t *testing.T) {
+	audio := newTestFile(t, 1000, 2, 3000, sine(50, 1000))
+	original := audio.BytesCopy()
+
+	if err := audio.Mute(2*time.Second, time.Second); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err := audio.Mute(time.Second, 4*time.Second); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err := audio.Mute(time.Second, 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	data := audio.Bytes()
+	if !bytes.Equal(data[:4000], original[:4000]) || !bytes.Equal(data[8000:], original[8000:]) {
+		t.Fatalf("the audio out of the range must be unchanged")
+	}
+	for i, b := range data[4000:8000] {
+		if b != 0 {
+			t.Fatalf("[%v] expected: 0 actual: %v", 4000+i, b)
+		}
+	}
+
+	u8, _ := NewSineWave(50, time.Second, 1000, 8, 1)
+	if err := u8.Mute(0, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range u8.Bytes() {
+		if b != 0x80 {
+			t.Fatalf("[%v] expected: 0x80 actual: %#x", i, b)
+		}
+	}
+	return
+}
+
+func TestBleep(t *testing.T) {
+	audio := newTestFile(t, 44100, 2, 44100*3, sine(220, 44100))
+
+	if err := audio.Bleep(time.Second, 2*time.Second, 0); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err := audio.Bleep(time.Second, 2*time.Second, 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	bleep := audio.empty()
+	bleep.Write(audio.Bytes()[44100*4 : 44100*8])
+
+	pitch, err := bleep.EstimatePitch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(pitch-1000) > 5 {
+		t.Fatalf("expected: 1000 actual: %v", pitch)
+	}
+
+	s32 := bleep.Int32s()
+	for i := 0; i < len(s32); i += 2 {
+		if s32[i] != s32[i+1] {
+			t.Fatalf("[%v] every channel must have the same tone", i)
+		}
+	}
+	return
+}
+
+func TestPrepend(t *testing.T) {
+	audio := newTestFile(t, 8000, 2, 800, sine(440, 8000))
+	intro := newTestFile(t, 8000, 2, 400, sine(880, 8000))
+	original := audio.BytesCopy()
+
+	if err := audio.Prepend(newTestFile(t, 8000, 1, 400, sine(880, 8000))); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err := audio.Prepend(intro); err != nil {
+		t.Fatal(err)
+	}
+	if audio.Length() != len(original)+intro.Length() {
+		t.Fatalf("expected: %v actual: %v", len(original)+intro.Length(), audio.Length())
+	}
+	if !bytes.Equal(audio.Bytes(), append(intro.BytesCopy(), original...)) {
+		t.Fatalf("intro must be followed by the original audio")
+	}
+	return
+}
+
+func TestInsert(t *testing.T) {
+	audio := newTestFile(t, 1000, 2, 1000, sine(50, 1000))
+	clip := newTestFile(t, 1000, 2, 300, func(i, c int) float64 { return 0.25 })
+	original := audio.BytesCopy()
+
+	if err := audio.Insert(-time.Second, clip); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err := audio.Insert(400*time.Millisecond, clip); err != nil {
+		t.Fatal(err)
+	}
+	if audio.Frames() != 1300 {
+		t.Fatalf("expected: 1300 actual: %v", audio.Frames())
+	}
+
+	data := audio.Bytes()
+	if !bytes.Equal(data[:1600], original[:1600]) {
+		t.Fatalf("the audio before the clip must be unchanged")
+	}
+	if !bytes.Equal(data[1600:2800], clip.Bytes()) {
+		t.Fatalf("the clip must be inserted at 400 ms")
+	}
+	if !bytes.Equal(data[2800:], original[1600:]) {
+		t.Fatalf("the audio after the clip must be unchanged")
+	}
+
+	if err := audio.Insert(time.Hour, clip); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(audio.Bytes(), clip.Bytes()) || audio.Frames() != 1600 {
+		t.Fatalf("the clip must be appended")
+	}
+	return
+}
+
+func TestDelete(t *testing.T) {
+	audio := newTestFile(t, 1000, 2, 3000, sine(50, 1000))
+	original := audio.BytesCopy()
+
+	if err := audio.Delete(time.Second, 5*time.Second); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err := audio.Delete(time.Second, 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if audio.Frames() != 2000 || audio.Length() != 8000 {
+		t.Fatalf("expected: 2000 frames actual: %v", audio.Frames())
+	}
+	if !bytes.Equal(audio.Bytes(), append(original[:4000:4000], original[8000:]...)) {
+		t.Fatalf("the remaining audio must be contiguous")
+	}
+	return
+}
+
+func TestDelete_Truncated(t *testing.T) {
+	// The truncated file has 10 frames although it declares 50 frames.
+	file, err := ioutil.ReadFile("./testdata/44100Hz-16bit-1ch-truncated.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := time.Second / 44100
+
+	if err = audio.Delete(0, 20*frame); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err = audio.Mute(0, 20*frame); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err = audio.Bleep(0, 20*frame, 1000); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err = audio.Mute(0, 10*frame); err != nil {
+		t.Fatal(err)
+	}
+
+	chunks, err := audio.Split(4 * frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 3 || chunks[2].Frames() != 2 {
+		t.Fatalf("expected: 3 chunks and the last one has 2 frames")
+	}
+
+	if err = audio.Delete(0, 4*frame); err != nil {
+		t.Fatal(err)
+	}
+	if len(audio.Bytes()) != 12 {
+		t.Fatalf("expected: 12 actual: %v", len(audio.Bytes()))
+	}
+	return
+}
+
+func TestResample(t *testing.T) {
+	audio := newTestFile(t, 44100, 2, 44100, sine(440, 44100))
+
+	if _, err := audio.Resample(0); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+
+	resampled, err := audio.Resample(22050)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resampled.SamplesPerSec() != 22050 || resampled.Frames() != 22050 {
+		t.Fatalf("expected: 22050 Hz and 22050 frames actual: %v", resampled)
+	}
+	if resampled.Duration() != audio.Duration() {
+		t.Fatalf("expected: %v actual: %v", audio.Duration(), resampled.Duration())
+	}
+
+	pitch, err := resampled.EstimatePitch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(pitch-440) > 3 {
+		t.Fatalf("expected: 440 actual: %v", pitch)
+	}
+	return
+}
+
+func TestClampSampleRate(t *testing.T) {
+	tests := []struct {
+		rate     int
+		expected int
+	}{
+		{8000, 16000},
+		{44100, 44100},
+		{192000, 48000},
+	}
+	for _, test := range tests {
+		audio := newTestFile(t, test.rate, 1, test.rate/10, sine(440, test.rate))
+
+		clamped, err := audio.ClampSampleRate(16000, 48000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if clamped.SamplesPerSec() != test.expected || clamped.Duration() != audio.Duration() {
+			t.Fatalf("expected: %v Hz actual: %v", test.expected, clamped)
+		}
+		if clamped == audio {
+			t.Fatalf("new File must be returned")
+		}
+	}
+
+	audio := newTestFile(t, 44100, 1, 100, sine(440, 44100))
+	if _, err := audio.ClampSampleRate(48000, 16000); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
+
+func TestResampleSinc(t *testing.T) {
+	rms := func(audio *File) float64 {
+		f64 := audio.Float64s()
+		sum := 0.0
+		for _, f := range f64 {
+			sum += f * f
+		}
+		return math.Sqrt(sum / float64(len(f64)))
+	}
+
+	// 15 kHz is above the Nyquist frequency of 22050 Hz, so it must be removed rather than aliased.
+	high := newTestFile(t, 44100, 2, 44100, sine(15000, 44100))
+	if _, err := high.ResampleSinc(0, 32); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if _, err := high.ResampleSinc(22050, 1); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+
+	sinc, err := high.ResampleSinc(22050, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	linear, err := high.Resample(22050)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sinc.SamplesPerSec() != 22050 || sinc.Frames() != linear.Frames() {
+		t.Fatalf("expected: %v actual: %v", linear, sinc)
+	}
+	if rms(sinc) > 0.1*rms(linear) {
+		t.Fatalf("expected: less than %v actual: %v", 0.1*rms(linear), rms(sinc))
+	}
+
+	// 1 kHz passes through.
+	low := newTestFile(t, 44100, 2, 44100, sine(1000, 44100))
+	if sinc, err = low.ResampleSinc(22050, 32); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(rms(sinc)-rms(low)) > 0.02*rms(low) {
+		t.Fatalf("expected: %v actual: %v", rms(low), rms(sinc))
+	}
+	return
+}
+
+func TestResampleToStandard(t *testing.T) {
+	audio := newTestFile(t, 96000, 2, 9600, sine(440, 96000))
+
+	if _, err := audio.ResampleToStandard(32000); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+
+	resampled, err := audio.ResampleToStandard(48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resampled.SamplesPerSec() != 48000 {
+		t.Fatalf("expected: 48000 actual: %v", resampled.SamplesPerSec())
+	}
+	if resampled.Frames() != 4800 {
+		t.Fatalf("expected: 4800 actual: %v", resampled.Frames())
+	}
+	return
+}
+
+func TestMap(t *testing.T) {
+	audio := newTestFile(t, 44100, 2, 4410, sine(440, 44100))
+	expected := audio.BytesCopy()
+
+	audio.Map(func(f float64) float64 { return f })
+	if !bytes.Equal(expected, audio.Bytes()) {
+		t.Fatalf("identity function must not change the audio")
+	}
+
+	original := audio.Float64s()
+	audio.Map(func(f float64) float64 { return f * 0.5 })
+	for i, f := range audio.Float64s() {
+		if math.Abs(f-original[i]*0.5) > 1.0/32768 {
+			t.Fatalf("[%v] expected: %v actual: %v", i, original[i]*0.5, f)
+		}
+	}
+
+	audio.Map(func(f float64) float64 { return 2 })
+	for i, s := range audio.Int16s() {
+		if s != math.MaxInt16 {
+			t.Fatalf("[%v] expected: %v actual: %v", i, math.MaxInt16, s)
+		}
+	}
+	return
+}
+
+func TestMapFrames(t *testing.T) {
+	left := sine(440, 44100)
+	right := sine(1000, 44100)
+	fn := func(i, c int) float64 {
+		if c == 0 {
+			return left(i, c)
+		}
+		return right(i, c)
+	}
+	audio := newTestFile(t, 44100, 2, 4410, fn)
+	expected := newTestFile(t, 44100, 2, 4410, fn)
+
+	if err := audio.MapFrames(func(frame []float64) []float64 {
+		return frame[:1]
+	}); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if !bytes.Equal(audio.Bytes(), expected.Bytes()) {
+		t.Fatalf("the audio must be unchanged on error")
+	}
+
+	if err := audio.MapFrames(func(frame []float64) []float64 {
+		return []float64{frame[1], frame[0]}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := expected.SwapChannels(0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(audio.Bytes(), expected.Bytes()) {
+		t.Fatalf("channels must be swapped")
+	}
+	return
+}
+
+func TestSwapSampleBytes(t *testing.T) {
+	for _, bits := range []int{16, 24, 32} {
+		audio, err := NewWave(Sine, 440, 100*time.Millisecond, 44100, bits, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		original := audio.BytesCopy()
+
+		if audio.DetectEndianness() != binary.LittleEndian {
+			t.Fatalf("expected: little endian actual: %v (%v bit)", audio.DetectEndianness(), bits)
+		}
+
+		audio.SwapSampleBytes()
+		if bytes.Equal(audio.Bytes(), original) {
+			t.Fatalf("samples must be swapped (%v bit)", bits)
+		}
+		if audio.DetectEndianness() != binary.BigEndian {
+			t.Fatalf("expected: big endian actual: %v (%v bit)", audio.DetectEndianness(), bits)
+		}
+
+		audio.SwapSampleBytes()
+		if !bytes.Equal(audio.Bytes(), original) {
+			t.Fatalf("swapping twice must restore the original (%v bit)", bits)
+		}
+	}
+	return
+}
@@ -0,0 +1,374 @@
+package wav
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// BitCrush quantizes the samples to bits of effective resolution by masking the low bits.
+// The bit depth of the audio is not changed. The bits must be between 1 and BitsPerSample().
+func (v *File) BitCrush(bits int) error {
+	if bits < 1 || bits > v.BitsPerSample() {
+		return fmt.Errorf("wav: invalid bits (%v bit)", bits)
+	}
+
+	const scale = 1 << 31
+	mask := int32(-1) << uint(32-bits)
+	s32 := v.Int32s()
+	f64 := make([]float64, len(s32))
+
+	for i, s := range s32 {
+		f64[i] = float64(s&mask) / scale
+	}
+	v.setFloat64s(f64)
+
+	return nil
+}
+
+// Saturate applies tanh soft clipping scaled by drive.
+// The larger drive saturates the audio harder, and the drive 0 leaves the audio untouched.
+// The output never exceeds full scale.
+func (v *File) Saturate(drive float64) error {
+	if drive < 0 || math.IsInf(drive, 0) || math.IsNaN(drive) {
+		return fmt.Errorf("wav: invalid drive (%v)", drive)
+	}
+	if drive == 0 {
+		return nil
+	}
+
+	norm := math.Tanh(drive)
+	f64 := v.Float64s()
+
+	for i, f := range f64 {
+		f64[i] = math.Tanh(drive*f) / norm
+	}
+	v.setFloat64s(f64)
+
+	return nil
+}
+
+// ApplyEnvelope multiplies the audio by the ADSR envelope.
+// The gain rises linearly from 0 to 1 during attack, falls to sustainLevel during decay,
+// holds sustainLevel during sustain and falls to 0 during release. The audio after release is silenced.
+// If the total time of the envelope exceeds the audio, each segment is shortened proportionally.
+func (v *File) ApplyEnvelope(attack, decay, sustain, release time.Duration, sustainLevel float64) error {
+	if attack < 0 || decay < 0 || sustain < 0 || release < 0 {
+		return fmt.Errorf("wav: invalid envelope (%v, %v, %v, %v)", attack, decay, sustain, release)
+	}
+	if sustainLevel < 0 || sustainLevel > 1 {
+		return fmt.Errorf("wav: invalid sustain level (%v)", sustainLevel)
+	}
+
+	frames := v.Frames()
+	segments := []float64{
+		float64(v.framesOf(attack)),
+		float64(v.framesOf(decay)),
+		float64(v.framesOf(sustain)),
+		float64(v.framesOf(release)),
+	}
+	total := segments[0] + segments[1] + segments[2] + segments[3]
+	if total > float64(frames) {
+		for i := range segments {
+			segments[i] *= float64(frames) / total
+		}
+	}
+	a, d, s, r := segments[0], segments[1], segments[2], segments[3]
+
+	gain := func(i float64) float64 {
+		switch {
+		case i < a:
+			return i / a
+		case i < a+d:
+			return 1 - (1-sustainLevel)*(i-a)/d
+		case i < a+d+s:
+			return sustainLevel
+		case i < a+d+s+r:
+			return sustainLevel * (1 - (i-a-d-s)/r)
+		}
+		return 0
+	}
+
+	channels := v.Channels()
+	f64 := v.Float64s()
+
+	for i := 0; i < frames; i++ {
+		g := gain(float64(i))
+		for c := 0; c < channels; c++ {
+			f64[i*channels+c] *= g
+		}
+	}
+	v.setFloat64s(f64)
+
+	return nil
+}
+
+// smoothing returns the coefficient of the one pole filter whose time constant is d.
+// The duration 0 returns 0, which means no smoothing.
+func (v *File) smoothing(d time.Duration) float64 {
+	frames := d.Seconds() * float64(v.SamplesPerSec())
+	if frames <= 0 {
+		return 0
+	}
+	return math.Exp(-1 / frames)
+}
+
+// Gate silences the frames whose level falls below threshold.
+// The level is the peak of the normalized absolute amplitude among the channels, which decays with release.
+// The gate opens within attack and closes within release, so that the gain does not chatter.
+func (v *File) Gate(threshold float64, attack, release time.Duration) error {
+	if threshold < 0 || threshold > 1 {
+		return fmt.Errorf("wav: invalid threshold (%v)", threshold)
+	}
+	if attack < 0 || release < 0 {
+		return fmt.Errorf("wav: invalid attack or release (%v, %v)", attack, release)
+	}
+
+	a := v.smoothing(attack)
+	r := v.smoothing(release)
+	channels := v.Channels()
+	frames := v.Frames()
+	f64 := v.Float64s()
+
+	level, gain := 0.0, 0.0
+	for i := 0; i < frames; i++ {
+		peak := 0.0
+		for c := 0; c < channels; c++ {
+			peak = math.Max(peak, math.Abs(f64[i*channels+c]))
+		}
+		level = math.Max(peak, level*r)
+
+		target, coef := 0.0, r
+		if level >= threshold {
+			target, coef = 1, a
+		}
+		gain = target + (gain-target)*coef
+
+		for c := 0; c < channels; c++ {
+			f64[i*channels+c] *= gain
+		}
+	}
+	v.setFloat64s(f64)
+
+	return nil
+}
+
+// Compress applies the feed-forward compressor.
+// The level above thresholdDB (dBFS) is reduced by ratio, for example the ratio 4 turns 8 dB over the threshold into 2 dB.
+// The level is the peak of the normalized absolute amplitude among the channels, which follows rises within attack
+// and falls within release.
+func (v *File) Compress(thresholdDB, ratio float64, attack, release time.Duration) error {
+	if thresholdDB > 0 || math.IsNaN(thresholdDB) {
+		return fmt.Errorf("wav: invalid threshold (%v dB)", thresholdDB)
+	}
+	if ratio < 1 || math.IsNaN(ratio) {
+		return fmt.Errorf("wav: invalid ratio (%v)", ratio)
+	}
+	if attack < 0 || release < 0 {
+		return fmt.Errorf("wav: invalid attack or release (%v, %v)", attack, release)
+	}
+
+	a := v.smoothing(attack)
+	r := v.smoothing(release)
+	channels := v.Channels()
+	frames := v.Frames()
+	f64 := v.Float64s()
+
+	level := 0.0
+	for i := 0; i < frames; i++ {
+		peak := 0.0
+		for c := 0; c < channels; c++ {
+			peak = math.Max(peak, math.Abs(f64[i*channels+c]))
+		}
+		if peak > level {
+			level = peak + (level-peak)*a
+		} else {
+			level = peak + (level-peak)*r
+		}
+
+		over := 20*math.Log10(level) - thresholdDB
+		if over <= 0 {
+			continue
+		}
+		gain := math.Pow(10, -over*(1-1/ratio)/20)
+		for c := 0; c < channels; c++ {
+			f64[i*channels+c] *= gain
+		}
+	}
+	v.setFloat64s(f64)
+
+	return nil
+}
+
+// Limit applies the brickwall limiter which keeps the sample peak at or below ceilingDB (dBFS).
+// The gain reduction starts lookahead before each peak and recovers within lookahead after it,
+// so that the transients do not exceed the ceiling and the gain changes smoothly.
+// Note that the inter-sample peaks are not detected, so TruePeak may slightly exceed the ceiling.
+func (v *File) Limit(ceilingDB float64, lookahead time.Duration) error {
+	if ceilingDB > 0 || math.IsNaN(ceilingDB) || math.IsInf(ceilingDB, -1) {
+		return fmt.Errorf("wav: invalid ceiling (%v dB)", ceilingDB)
+	}
+	if lookahead < 0 {
+		return fmt.Errorf("wav: invalid lookahead (%v)", lookahead)
+	}
+
+	ceiling := math.Pow(10, ceilingDB/20)
+	size := v.framesOf(lookahead)
+	channels := v.Channels()
+	frames := v.Frames()
+	f64 := v.Float64s()
+
+	gains := make([]float64, frames)
+	for i := range gains {
+		gains[i] = 1
+	}
+	for i := 0; i < frames; i++ {
+		peak := 0.0
+		for c := 0; c < channels; c++ {
+			peak = math.Max(peak, math.Abs(f64[i*channels+c]))
+		}
+		if peak <= ceiling {
+			continue
+		}
+
+		// The gain ramps linearly down to the required gain at the peak and back up.
+		required := ceiling / peak
+		for j := i - size; j <= i+size; j++ {
+			if j < 0 || j >= frames {
+				continue
+			}
+			distance := math.Abs(float64(j - i))
+			gains[j] = math.Min(gains[j], required+(1-required)*distance/float64(size+1))
+		}
+	}
+
+	for i := 0; i < frames; i++ {
+		for c := 0; c < channels; c++ {
+			f64[i*channels+c] *= gains[i]
+		}
+	}
+	v.setFloat64s(f64)
+
+	return nil
+}
+
+// Declip reconstructs the peaks of the clipped samples which are detected by ClippingRuns.
+// Each clipped segment of each channel is replaced with the cubic curve through two samples before and two samples after it.
+// The runs longer than 10 milliseconds and the runs at the edges of the audio are left untouched.
+// Note that the reconstructed peaks exceed full scale, they are clamped unless the audio is IEEE float.
+func (v *File) Declip() {
+	runs := v.ClippingRuns()
+	if len(runs) == 0 {
+		return
+	}
+
+	maxLength := v.framesOf(10 * time.Millisecond)
+	high := v.fullScale()
+
+	channels := v.Channels()
+	frames := v.Frames()
+	s32 := v.Int32s()
+	f64 := v.Float64s()
+
+	clipped := func(i, c int) bool {
+		s := s32[i*channels+c]
+		return s >= high || s <= -high
+	}
+
+	// Lagrange interpolation through (x[k], y[k]).
+	cubic := func(x [4]float64, y [4]float64, t float64) float64 {
+		sum := 0.0
+		for k := 0; k < 4; k++ {
+			term := y[k]
+			for j := 0; j < 4; j++ {
+				if j != k {
+					term *= (t - x[j]) / (x[k] - x[j])
+				}
+			}
+			sum += term
+		}
+		return sum
+	}
+
+	for _, run := range runs {
+		if run.Length > maxLength {
+			continue
+		}
+		for c := 0; c < channels; c++ {
+			for i := run.Start; i < run.Start+run.Length; i++ {
+				if !clipped(i, c) {
+					continue
+				}
+				start, end := i, i
+				for end < run.Start+run.Length && clipped(end, c) {
+					end++
+				}
+				i = end
+
+				if start < 2 || end+1 >= frames {
+					continue
+				}
+				x := [4]float64{float64(start - 2), float64(start - 1), float64(end), float64(end + 1)}
+				y := [4]float64{}
+				for k := range x {
+					y[k] = f64[int(x[k])*channels+c]
+				}
+				for j := start; j < end; j++ {
+					f64[j*channels+c] = cubic(x, y, float64(j))
+				}
+			}
+		}
+	}
+	v.setFloat64s(f64)
+}
+
+// GainPoint is a breakpoint of the gain automation.
+type GainPoint struct {
+	At   time.Duration // The position of the breakpoint.
+	Gain float64       // The gain factor at the position.
+}
+
+// AutomateGain multiplies the audio by the gain which is linearly interpolated between the points.
+// The gain before the first point and after the last point is held. The points must be sorted by At.
+// The samples out of range are clamped.
+func (v *File) AutomateGain(points []GainPoint) error {
+	if len(points) == 0 {
+		return fmt.Errorf("wav: no gain points")
+	}
+	for i := 1; i < len(points); i++ {
+		if points[i].At < points[i-1].At {
+			return fmt.Errorf("wav: gain points are not sorted (%v is after %v)", points[i-1].At, points[i].At)
+		}
+	}
+
+	channels := v.Channels()
+	frames := v.Frames()
+	f64 := v.Float64s()
+	rate := float64(v.SamplesPerSec())
+	p := 0
+
+	for i := 0; i < frames; i++ {
+		t := float64(i) / rate
+		for p < len(points) && points[p].At.Seconds() <= t {
+			p++
+		}
+
+		var gain float64
+		switch {
+		case p == 0:
+			gain = points[0].Gain
+		case p == len(points):
+			gain = points[p-1].Gain
+		default:
+			a, b := points[p-1], points[p]
+			gain = a.Gain + (b.Gain-a.Gain)*(t-a.At.Seconds())/(b.At-a.At).Seconds()
+		}
+
+		for c := 0; c < channels; c++ {
+			f64[i*channels+c] *= gain
+		}
+	}
+	v.setFloat64s(f64)
+
+	return nil
+}
@@ -0,0 +1,282 @@
+package wav
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func distinctInt32s(s32 []int32) int {
+	values := map[int32]bool{}
+	for _, s := range s32 {
+		values[s] = true
+	}
+	return len(values)
+}
+
+func rmsOf(f64 []float64) float64 {
+	sum := 0.0
+	for _, f := range f64 {
+		sum += f * f
+	}
+	return math.Sqrt(sum / float64(len(f64)))
+}
+
+func TestBitCrush(t *testing.T) {
+	audio := newTestFile(t, 44100, 1, 44100, sine(440, 44100))
+
+	if err := audio.BitCrush(0); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err := audio.BitCrush(17); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+
+	before := distinctInt32s(audio.Int32s())
+	if err := audio.BitCrush(4); err != nil {
+		t.Fatal(err)
+	}
+	after := distinctInt32s(audio.Int32s())
+
+	if after > 16 || after >= before {
+		t.Fatalf("expected: at most 16 distinct values actual: %v (before %v)", after, before)
+	}
+	if audio.BitsPerSample() != 16 {
+		t.Fatalf("expected: 16 actual: %v", audio.BitsPerSample())
+	}
+	return
+}
+
+func TestSaturate(t *testing.T) {
+	audio := newTestFile(t, 44100, 1, 4410, sine(440, 44100))
+	original := newTestFile(t, 44100, 1, 4410, sine(440, 44100))
+
+	if err := audio.Saturate(0); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(audio.Bytes(), original.Bytes()) {
+		t.Fatalf("drive 0 must not change the audio")
+	}
+	if err := audio.Saturate(20); err != nil {
+		t.Fatal(err)
+	}
+
+	peak := 0.0
+	for _, f := range audio.Float64s() {
+		peak = math.Max(peak, math.Abs(f))
+	}
+	if peak < 0.99 || peak > 1 {
+		t.Fatalf("expected: 0.99 <= peak <= 1.0 actual: %v", peak)
+	}
+	return
+}
+
+func TestApplyEnvelope(t *testing.T) {
+	audio := newTestFile(t, 1000, 2, 1000, func(i, c int) float64 { return 0.5 })
+
+	if err := audio.ApplyEnvelope(-time.Second, 0, 0, 0, 0.5); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err := audio.ApplyEnvelope(0, 0, 0, 0, 1.5); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+
+	attack := 100 * time.Millisecond
+	if err := audio.ApplyEnvelope(attack, 200*time.Millisecond, 300*time.Millisecond, 400*time.Millisecond, 0.5); err != nil {
+		t.Fatal(err)
+	}
+
+	f64 := audio.Float64s()
+	if f := f64[100*2]; math.Abs(f-0.5) > 1e-3 {
+		t.Fatalf("expected: 0.5 actual: %v", f)
+	}
+	if f := f64[450*2]; math.Abs(f-0.25) > 1e-3 {
+		t.Fatalf("expected: 0.25 actual: %v", f)
+	}
+	if f := f64[len(f64)-1]; math.Abs(f) > 1e-3 {
+		t.Fatalf("expected: 0 actual: %v", f)
+	}
+	return
+}
+
+func TestApplyEnvelope_Clamp(t *testing.T) {
+	audio := newTestFile(t, 1000, 1, 1000, func(i, c int) float64 { return 0.5 })
+
+	// The envelope is twice as long as the audio, so every segment is halved.
+	if err := audio.ApplyEnvelope(200*time.Millisecond, 0, 1600*time.Millisecond, 200*time.Millisecond, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	f64 := audio.Float64s()
+	if f := f64[100]; math.Abs(f-0.5) > 1e-3 {
+		t.Fatalf("expected: 0.5 actual: %v", f)
+	}
+	if f := f64[999]; math.Abs(f) > 0.01 {
+		t.Fatalf("expected: 0 actual: %v", f)
+	}
+	return
+}
+
+func TestGate(t *testing.T) {
+	noise := rand.New(rand.NewSource(1))
+	tone := sine(440, 8000)
+	// noise, tone over noise, noise, tone over noise (250 milliseconds each)
+	audio := newTestFile(t, 8000, 1, 8000, func(i, c int) float64 {
+		f := 0.02 * (noise.Float64()*2 - 1)
+		if (i/2000)%2 == 1 {
+			f += tone(i, c)
+		}
+		return f
+	})
+	original := audio.Float64s()
+
+	if err := audio.Gate(0.1, -time.Millisecond, 0); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err := audio.Gate(0.1, time.Millisecond, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	f64 := audio.Float64s()
+	// The noise after the first tone.
+	if actual := rmsOf(f64[5000:6000]); actual > 1e-3 {
+		t.Fatalf("expected: less than 0.001 actual: %v", actual)
+	}
+	// The second tone.
+	expected := rmsOf(original[6500:8000])
+	if actual := rmsOf(f64[6500:8000]); math.Abs(actual-expected) > 0.01*expected {
+		t.Fatalf("expected: %v actual: %v", expected, actual)
+	}
+	return
+}
+
+func TestCompress(t *testing.T) {
+	tone := sine(440, 8000)
+	// loud, quiet (500 milliseconds each)
+	audio := newTestFile(t, 8000, 2, 8000, func(i, c int) float64 {
+		if i < 4000 {
+			return 1.8 * tone(i, c)
+		}
+		return 0.1 * tone(i, c)
+	})
+	original := audio.Float64s()
+
+	if err := audio.Compress(-20, 0.5, 0, 0); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err := audio.Compress(-20, 4, time.Millisecond, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	f64 := audio.Float64s()
+	// The loud section is about 19 dB over the threshold, which is reduced to about 5 dB.
+	if actual := rmsOf(f64[2000:8000]); actual > 0.15 {
+		t.Fatalf("expected: less than 0.15 actual: %v", actual)
+	}
+	expected := rmsOf(original[12000:16000])
+	if actual := rmsOf(f64[12000:16000]); math.Abs(actual-expected) > 0.05*expected {
+		t.Fatalf("expected: %v actual: %v", expected, actual)
+	}
+	return
+}
+
+func TestLimit(t *testing.T) {
+	tone := sine(440, 8000)
+	// quiet, loud (500 milliseconds each)
+	audio := newTestFile(t, 8000, 2, 8000, func(i, c int) float64 {
+		if i < 4000 {
+			return 0.2 * tone(i, c)
+		}
+		return 1.9 * tone(i, c)
+	})
+	original := audio.Float64s()
+
+	if err := audio.Limit(1, 0); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err := audio.Limit(-6, 5*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	// The quantization may round up by half LSB.
+	ceiling := math.Pow(10, -6.0/20) + 1.0/32768
+	f64 := audio.Float64s()
+	for i, f := range f64 {
+		if math.Abs(f) > ceiling {
+			t.Fatalf("[%v] expected: less than %v actual: %v", i, ceiling, f)
+		}
+	}
+	for i := 0; i < 7000; i++ {
+		if f64[i] != original[i] {
+			t.Fatalf("[%v] expected: %v actual: %v", i, original[i], f64[i])
+		}
+	}
+	return
+}
+
+func TestDeclip(t *testing.T) {
+	original := make([]float64, 4410)
+	clipped := make([]float64, len(original))
+	for i := range original {
+		original[i] = 1.2 * math.Sin(2*math.Pi*440*float64(i)/44100)
+		clipped[i] = math.Max(-1, math.Min(1, original[i]))
+	}
+
+	audio, err := New(44100, 32, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio.subFormat = WAVE_FORMAT_IEEE_FLOAT
+	audio.setFloat64s(clipped)
+
+	audio.Declip()
+
+	f64 := audio.Float64s()
+	peak := 0.0
+	before, after := 0.0, 0.0
+	for i := range f64 {
+		peak = math.Max(peak, math.Abs(f64[i]))
+		before += (clipped[i] - original[i]) * (clipped[i] - original[i])
+		after += (f64[i] - original[i]) * (f64[i] - original[i])
+		if math.Abs(clipped[i]) < 1 && float32(f64[i]) != float32(clipped[i]) {
+			t.Fatalf("[%v] expected: %v actual: %v", i, clipped[i], f64[i])
+		}
+	}
+	if peak <= 1 {
+		t.Fatalf("expected: greater than 1 actual: %v", peak)
+	}
+	if after >= before/2 {
+		t.Fatalf("expected: less than %v actual: %v", before/2, after)
+	}
+	return
+}
+
+func TestAutomateGain(t *testing.T) {
+	audio := newTestFile(t, 1000, 2, 1000, func(i, c int) float64 { return 0.5 })
+
+	if err := audio.AutomateGain(nil); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if err := audio.AutomateGain([]GainPoint{{At: time.Second, Gain: 1}, {At: 0, Gain: 0}}); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+
+	// The last frame is at 999 milliseconds.
+	if err := audio.AutomateGain([]GainPoint{{At: 0, Gain: 0}, {At: 999 * time.Millisecond, Gain: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	f64 := audio.Float64s()
+	if f64[0] != 0 || f64[1] != 0 {
+		t.Fatalf("expected: 0 actual: %v", f64[:2])
+	}
+	if f := f64[len(f64)-1]; math.Abs(f-0.5) > 1e-4 {
+		t.Fatalf("expected: 0.5 actual: %v", f)
+	}
+	if f := f64[500*2]; math.Abs(f-0.25) > 1e-3 {
+		t.Fatalf("expected: 0.25 actual: %v", f)
+	}
+	return
+}
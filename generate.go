@@ -0,0 +1,122 @@
+package wav
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Waveform represents the shape of the periodic signal generated by NewWave.
+type Waveform int
+
+const (
+	Sine Waveform = iota
+	Square
+	Sawtooth
+)
+
+// NewWave creates a File which contains the full scale periodic signal of the waveform w.
+// The same signal is written to every channel.
+func NewWave(w Waveform, freq float64, d time.Duration, samplesPerSec, bits, channels int) (*File, error) {
+	if freq <= 0 || math.IsInf(freq, 0) || math.IsNaN(freq) {
+		return nil, fmt.Errorf("wav: invalid frequency (%v Hz)", freq)
+	}
+	if d < 0 {
+		return nil, fmt.Errorf("wav: invalid duration (%v)", d)
+	}
+	if w < Sine || w > Sawtooth {
+		return nil, fmt.Errorf("wav: invalid waveform (%v)", w)
+	}
+
+	audio, err := New(samplesPerSec, bits, channels)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := audio.framesOf(d)
+	f64 := make([]float64, frames*channels)
+
+	for i := 0; i < frames; i++ {
+		phase := math.Mod(freq*float64(i)/float64(samplesPerSec), 1)
+
+		f := math.Sin(2 * math.Pi * phase)
+		switch w {
+		case Square:
+			f = 1
+			if phase >= 0.5 {
+				f = -1
+			}
+		case Sawtooth:
+			f = 2*phase - 1
+		}
+		for c := 0; c < channels; c++ {
+			f64[i*channels+c] = f
+		}
+	}
+	audio.setFloat64s(f64)
+
+	return audio, nil
+}
+
+// NewSineWave creates a File which contains the full scale sine wave.
+func NewSineWave(freq float64, d time.Duration, samplesPerSec, bits, channels int) (*File, error) {
+	return NewWave(Sine, freq, d, samplesPerSec, bits, channels)
+}
+
+// noiseAmplitude is the peak amplitude of the generated noise, about -6 dBFS.
+const noiseAmplitude = 0.5
+
+// newNoise creates a File whose channels are filled by the independent noise returned by next.
+func newNoise(d time.Duration, samplesPerSec, bits, channels int, next func(c int) float64) (*File, error) {
+	if d < 0 {
+		return nil, fmt.Errorf("wav: invalid duration (%v)", d)
+	}
+
+	audio, err := New(samplesPerSec, bits, channels)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := audio.framesOf(d)
+	f64 := make([]float64, frames*channels)
+
+	for i := range f64 {
+		f64[i] = next(i % channels)
+	}
+	audio.setFloat64s(f64)
+
+	return audio, nil
+}
+
+// NewWhiteNoise creates a File which contains the white noise whose peak is about -6 dBFS.
+func NewWhiteNoise(d time.Duration, samplesPerSec, bits, channels int) (*File, error) {
+	return newNoise(d, samplesPerSec, bits, channels, func(c int) float64 {
+		return noiseAmplitude * (2*rand.Float64() - 1)
+	})
+}
+
+// NewPinkNoise creates a File which contains the pink (1/f) noise whose peak is about -6 dBFS.
+// The white noise is filtered with Paul Kellet's refined method.
+func NewPinkNoise(d time.Duration, samplesPerSec, bits, channels int) (*File, error) {
+	if channels < 0 {
+		channels = 0
+	}
+	state := make([][7]float64, channels)
+
+	return newNoise(d, samplesPerSec, bits, channels, func(c int) float64 {
+		white := 2*rand.Float64() - 1
+		b := &state[c]
+
+		b[0] = 0.99886*b[0] + white*0.0555179
+		b[1] = 0.99332*b[1] + white*0.0750759
+		b[2] = 0.96900*b[2] + white*0.1538520
+		b[3] = 0.86650*b[3] + white*0.3104856
+		b[4] = 0.55000*b[4] + white*0.5329522
+		b[5] = -0.7616*b[5] - white*0.0168980
+		pink := b[0] + b[1] + b[2] + b[3] + b[4] + b[5] + b[6] + white*0.5362
+		b[6] = white * 0.115926
+
+		return noiseAmplitude * pink * 0.11
+	})
+}
@@ -0,0 +1,96 @@
+package wav
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+	"time"
+)
+
+func TestNewSineWave(t *testing.T) {
+	audio, err := NewSineWave(1000, 500*time.Millisecond, 44100, 16, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audio.Frames() != 22050 {
+		t.Fatalf("expected: 22050 actual: %v", audio.Frames())
+	}
+
+	pitch, err := audio.EstimatePitch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(pitch-1000) > 5 {
+		t.Fatalf("expected: 1000 actual: %v", pitch)
+	}
+
+	if _, err = NewSineWave(0, time.Second, 44100, 16, 2); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if _, err = NewWave(Waveform(-1), 1000, time.Second, 44100, 16, 2); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
+
+func TestNewWave(t *testing.T) {
+	audio, err := NewWave(Square, 100, 100*time.Millisecond, 8000, 8, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, f := range audio.Float64s() {
+		if math.Abs(f) < 0.99 {
+			t.Fatalf("[%v] expected: full scale actual: %v", i, f)
+		}
+	}
+	return
+}
+
+// bandEnergyRatio returns the ratio of the spectral energy density
+// between 100-400 Hz band and 4000-16000 Hz band.
+func bandEnergyRatio(audio *File) float64 {
+	spectrum := fft(audio.mono())
+	binWidth := float64(audio.SamplesPerSec()) / float64(len(spectrum))
+
+	density := func(low, high float64) float64 {
+		sum := 0.0
+		n := 0
+		for k := int(low / binWidth); k < int(high/binWidth); k++ {
+			a := cmplx.Abs(spectrum[k])
+			sum += a * a
+			n++
+		}
+		return sum / float64(n)
+	}
+
+	return density(100, 400) / density(4000, 16000)
+}
+
+func TestNewWhiteNoise(t *testing.T) {
+	audio, err := NewWhiteNoise(time.Second, 44100, 16, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audio.Frames() != 44100 {
+		t.Fatalf("expected: 44100 actual: %v", audio.Frames())
+	}
+	if ratio := bandEnergyRatio(audio); ratio < 0.5 || ratio > 2 {
+		t.Fatalf("expected: flat spectrum actual: %v", ratio)
+	}
+	return
+}
+
+func TestNewPinkNoise(t *testing.T) {
+	audio, err := NewPinkNoise(time.Second, 44100, 16, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audio.Frames() != 44100 {
+		t.Fatalf("expected: 44100 actual: %v", audio.Frames())
+	}
+	// 1/f spectrum has about 16 times more energy density at the lower band.
+	if ratio := bandEnergyRatio(audio); ratio < 5 {
+		t.Fatalf("expected: 1/f spectrum actual: %v", ratio)
+	}
+	return
+}
@@ -0,0 +1,103 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+)
+
+// ID3Tags represents the common text frames of ID3v2 tag which is embedded in "id3 " chunk.
+type ID3Tags struct {
+	Title  string // TIT2
+	Artist string // TPE1
+	Album  string // TALB
+}
+
+// ID3 returns the tags parsed from "id3 " chunk, or nil if the audio has no such chunk.
+// Only ID3v2.3 and ID3v2.4 text frames are parsed. Marshal writes the chunk back as is.
+func (v *File) ID3() *ID3Tags {
+	if v.id3 == nil {
+		return nil
+	}
+
+	tags := &ID3Tags{}
+	tag := v.id3.data
+	if len(tag) < 10 || string(tag[0:3]) != "ID3" {
+		return tags
+	}
+
+	version := tag[3]
+	end := 10 + syncsafe(tag[6:10])
+	if end > len(tag) {
+		end = len(tag)
+	}
+
+	for offset := 10; offset+10 <= end; {
+		id := string(tag[offset : offset+4])
+		size := int(binary.BigEndian.Uint32(tag[offset+4 : offset+8]))
+		if version >= 4 {
+			size = syncsafe(tag[offset+4 : offset+8])
+		}
+		if id[0] == 0 || size < 0 || offset+10+size > end {
+			break
+		}
+
+		body := tag[offset+10 : offset+10+size]
+		switch id {
+		case "TIT2":
+			tags.Title = decodeID3Text(body)
+		case "TPE1":
+			tags.Artist = decodeID3Text(body)
+		case "TALB":
+			tags.Album = decodeID3Text(body)
+		}
+		offset += 10 + size
+	}
+
+	return tags
+}
+
+// syncsafe decodes 28 bit integer which is stored in 4 bytes of 7 bits.
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+// decodeID3Text decodes the body of ID3v2 text frame.
+func decodeID3Text(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	encoding, text := body[0], body[1:]
+
+	switch encoding {
+	case 0:
+		// ISO-8859-1
+		text = bytes.TrimRight(text, "\x00")
+		runes := make([]rune, len(text))
+		for i, b := range text {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	case 1, 2:
+		// UTF-16 with BOM, or UTF-16BE without BOM.
+		var order binary.ByteOrder = binary.BigEndian
+		if encoding == 1 && len(text) >= 2 {
+			if text[0] == 0xff && text[1] == 0xfe {
+				order = binary.LittleEndian
+			}
+			text = text[2:]
+		}
+		units := make([]uint16, len(text)/2)
+		for i := range units {
+			units[i] = order.Uint16(text[i*2:])
+		}
+		for len(units) > 0 && units[len(units)-1] == 0 {
+			units = units[:len(units)-1]
+		}
+		return string(utf16.Decode(units))
+	}
+
+	// UTF-8
+	return string(bytes.TrimRight(text, "\x00"))
+}
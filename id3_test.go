@@ -0,0 +1,48 @@
+package wav
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestID3(t *testing.T) {
+	filename := "./testdata/44100Hz-16bit-1ch-id3.wav"
+	file, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags := audio.ID3()
+	if tags == nil {
+		t.Fatalf("tags must not be nil (%v)", filename)
+	}
+	expected := ID3Tags{Title: "Sawtooth", Artist: "moutend", Album: "go-wav"}
+	if *tags != expected {
+		t.Fatalf("expected: %+v actual: %+v (%v)", expected, *tags, filename)
+	}
+	if audio.Length() != 8 {
+		t.Fatalf("expected: 8 actual: %v (%v)", audio.Length(), filename)
+	}
+
+	stream, err := Marshal(audio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(file, stream) {
+		t.Fatalf("id3 chunk must be preserved (%v)", filename)
+	}
+	if audio.MarshalSize() != len(stream) {
+		t.Fatalf("expected: %v actual: %v (%v)", len(stream), audio.MarshalSize(), filename)
+	}
+
+	plain, _ := New(44100, 16, 1)
+	if plain.ID3() != nil {
+		t.Fatalf("expected: nil actual: %+v", plain.ID3())
+	}
+	return
+}
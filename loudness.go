@@ -0,0 +1,187 @@
+package wav
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// biquad is a second order IIR filter in direct form I.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+}
+
+// apply filters x in place.
+func (f biquad) apply(x []float64) {
+	var x1, x2, y1, y2 float64
+
+	for i, x0 := range x {
+		y0 := f.b0*x0 + f.b1*x1 + f.b2*x2 - f.a1*y1 - f.a2*y2
+		x2, x1 = x1, x0
+		y2, y1 = y1, y0
+		x[i] = y0
+	}
+}
+
+// kWeighting returns the two stages of the K-weighting filter defined in ITU-R BS.1770
+// for the sample rate.
+func kWeighting(samplesPerSec int) []biquad {
+	fs := float64(samplesPerSec)
+
+	// Stage 1: high shelf filter which models the acoustic effect of the head.
+	f0 := 1681.974450955533
+	gain := 3.999843853973347
+	q := 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / fs)
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf := biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	// Stage 2: high pass filter (RLB weighting).
+	f0 = 38.13547087602444
+	q = 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / fs)
+	a0 = 1 + k/q + k*k
+	highpass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	return []biquad{shelf, highpass}
+}
+
+// channelWeights returns the weight of each channel used to sum the loudness.
+// The surround channels are weighted by 1.41 and LFE is excluded.
+func (v *File) channelWeights() []float64 {
+	channels := v.Channels()
+	weights := make([]float64, channels)
+	layout := v.SpeakerLayout()
+
+	for c := range weights {
+		weights[c] = 1.0
+		if channels <= 2 || len(layout) != channels {
+			continue
+		}
+		switch layout[c] {
+		case "LFE":
+			weights[c] = 0
+		case "BL", "BR", "SL", "SR":
+			weights[c] = 1.41
+		}
+	}
+
+	return weights
+}
+
+// IntegratedLoudness returns the integrated loudness in LUFS as defined in ITU-R BS.1770.
+// The audio is K-weighted and measured in 400 ms blocks with 75% overlap,
+// gated by the absolute threshold -70 LUFS and the relative threshold -10 LU.
+// It returns negative infinity if the audio is silent.
+func (v *File) IntegratedLoudness() (float64, error) {
+	const absoluteGate = -70.0
+	const relativeGate = -10.0
+
+	channels := v.Channels()
+	frames := v.Frames()
+	blockSize := v.framesOf(400 * time.Millisecond)
+	step := blockSize / 4
+
+	if channels == 0 || blockSize == 0 || frames < blockSize {
+		return 0, fmt.Errorf("wav: audio is too short to measure loudness")
+	}
+
+	f64 := v.Float64s()
+	filters := kWeighting(v.SamplesPerSec())
+	weights := v.channelWeights()
+	blocks := (frames-blockSize)/step + 1
+	powers := make([]float64, blocks)
+
+	for c := 0; c < channels; c++ {
+		x := make([]float64, frames)
+		for i := range x {
+			x[i] = f64[i*channels+c]
+		}
+		for _, filter := range filters {
+			filter.apply(x)
+		}
+		for j := 0; j < blocks; j++ {
+			sum := 0.0
+			for _, s := range x[j*step : j*step+blockSize] {
+				sum += s * s
+			}
+			powers[j] += weights[c] * sum / float64(blockSize)
+		}
+	}
+
+	loudness := func(power float64) float64 {
+		return -0.691 + 10*math.Log10(power)
+	}
+	gatedMean := func(threshold float64) (float64, int) {
+		sum, n := 0.0, 0
+		for _, power := range powers {
+			if loudness(power) > threshold {
+				sum += power
+				n++
+			}
+		}
+		if n == 0 {
+			return 0, 0
+		}
+		return sum / float64(n), n
+	}
+
+	power, n := gatedMean(absoluteGate)
+	if n == 0 {
+		return math.Inf(-1), nil
+	}
+	power, n = gatedMean(loudness(power) + relativeGate)
+	if n == 0 {
+		return math.Inf(-1), nil
+	}
+
+	return loudness(power), nil
+}
+
+// NormalizeLoudness applies the gain which brings the integrated loudness to targetLUFS.
+// To avoid clipping, the samples above -1 dBFS after the gain are softly limited to -1 dBFS.
+// It does nothing if the loudness is already within 0.1 LU of the target.
+func (v *File) NormalizeLoudness(targetLUFS float64) error {
+	const tolerance = 0.1
+
+	loudness, err := v.IntegratedLoudness()
+	if err != nil {
+		return err
+	}
+	if math.IsInf(loudness, -1) {
+		return fmt.Errorf("wav: cannot normalize silent audio")
+	}
+	if math.Abs(loudness-targetLUFS) <= tolerance {
+		return nil
+	}
+
+	gain := math.Pow(10, (targetLUFS-loudness)/20)
+	ceiling := math.Pow(10, -1.0/20)
+	knee := 0.9 * ceiling
+	f64 := v.Float64s()
+
+	for i, f := range f64 {
+		f *= gain
+		if a := math.Abs(f); a > knee {
+			f = math.Copysign(knee+(ceiling-knee)*math.Tanh((a-knee)/(ceiling-knee)), f)
+		}
+		f64[i] = f
+	}
+	v.setFloat64s(f64)
+
+	return nil
+}
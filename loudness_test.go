@@ -0,0 +1,61 @@
+package wav
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIntegratedLoudness(t *testing.T) {
+	// EBU Tech 3341: stereo sine wave, 1000 Hz, -23 dBFS reads -23.0 LUFS.
+	amplitude := math.Pow(10, -23.0/20)
+	audio := newTestFile(t, 48000, 2, 48000*5, func(i, c int) float64 {
+		return amplitude * math.Sin(2*math.Pi*1000*float64(i)/48000)
+	})
+
+	loudness, err := audio.IntegratedLoudness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(loudness-(-23)) > 0.2 {
+		t.Fatalf("expected: -23.0 actual: %v", loudness)
+	}
+
+	silent := newTestFile(t, 48000, 1, 48000, func(i, c int) float64 { return 0 })
+	if loudness, err = silent.IntegratedLoudness(); err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(loudness, -1) {
+		t.Fatalf("expected: -Inf actual: %v", loudness)
+	}
+
+	short := newTestFile(t, 48000, 1, 4800, sine(1000, 48000))
+	if _, err = short.IntegratedLoudness(); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
+
+func TestNormalizeLoudness(t *testing.T) {
+	amplitude := math.Pow(10, -30.0/20)
+	audio := newTestFile(t, 48000, 2, 48000*3, func(i, c int) float64 {
+		return amplitude * math.Sin(2*math.Pi*1000*float64(i)/48000)
+	})
+
+	if err := audio.NormalizeLoudness(-14); err != nil {
+		t.Fatal(err)
+	}
+
+	loudness, err := audio.IntegratedLoudness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(loudness-(-14)) > 0.5 {
+		t.Fatalf("expected: -14.0 actual: %v", loudness)
+	}
+
+	silent := newTestFile(t, 48000, 1, 48000, func(i, c int) float64 { return 0 })
+	if err = silent.NormalizeLoudness(-14); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
@@ -0,0 +1,88 @@
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+)
+
+// RawFormat describes the memory layout of the headerless audio samples which Raw returns.
+type RawFormat struct {
+	// BitsPerSample is the bit depth of the signed integer samples, either 8, 16, 24 or 32.
+	BitsPerSample int
+	// ByteOrder is the byte order of each sample. nil means binary.LittleEndian.
+	ByteOrder binary.ByteOrder
+	// Planar stores all samples of the first channel, then the second channel and so on.
+	// Otherwise the samples are interleaved frame by frame.
+	Planar bool
+}
+
+// SaveRaw writes the headerless audio samples to the file named path.
+func (v *File) SaveRaw(path string) error {
+	return ioutil.WriteFile(path, v.data, 0644)
+}
+
+// OpenRaw reads the headerless audio samples from the file named path and wraps them in a File with the supplied format.
+// The samples must be interleaved little endian linear PCM.
+func OpenRaw(path string, samplesPerSec, bits, channels int) (*File, error) {
+	if channels <= 0 {
+		return nil, fmt.Errorf("wav: invalid number of channels (%v)", channels)
+	}
+	if bits != 8 && bits != 16 && bits != 24 && bits != 32 {
+		return nil, fmt.Errorf("wav: invalid bits per sample (%v bit)", bits)
+	}
+
+	audio, err := New(samplesPerSec, bits, channels)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%audio.BlockAlign() != 0 {
+		return nil, fmt.Errorf("wav: size of %v (%v bytes) is not a multiple of block align (%v bytes)", path, len(data), audio.BlockAlign())
+	}
+	audio.Write(data)
+
+	return audio, nil
+}
+
+// Raw returns audio samples as byte slice which is encoded in format.
+// It generalizes S8, S16, S24 and S32, for example the format {16, binary.LittleEndian, false}
+// returns the same samples as S16.
+func (v *File) Raw(format RawFormat) ([]byte, error) {
+	bits := format.BitsPerSample
+	if bits != 8 && bits != 16 && bits != 24 && bits != 32 {
+		return nil, fmt.Errorf("wav: invalid bits per sample (%v bit)", bits)
+	}
+
+	bigEndian := format.ByteOrder == binary.BigEndian
+	width := bits / 8
+	channels := v.Channels()
+	if channels == 0 {
+		return nil, fmt.Errorf("wav: invalid number of channels (%v)", channels)
+	}
+	s32 := v.Int32s()
+	frames := len(s32) / channels
+	data := make([]byte, len(s32)*width)
+
+	for i, s := range s32 {
+		offset := i * width
+		if format.Planar {
+			offset = (i%channels*frames + i/channels) * width
+		}
+		s >>= uint(32 - bits)
+		for j := 0; j < width; j++ {
+			b := byte(s >> uint(8*j))
+			if bigEndian {
+				data[offset+width-1-j] = b
+			} else {
+				data[offset+j] = b
+			}
+		}
+	}
+
+	return data, nil
+}
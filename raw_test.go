@@ -0,0 +1,81 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveRaw(t *testing.T) {
+	filename := "./testdata/sawtooth.raw"
+	expected, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	audio, err := OpenRaw(filename, 44100, 16, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audio.Samples() != len(expected)/2 {
+		t.Fatalf("expected: %v actual: %v", len(expected)/2, audio.Samples())
+	}
+
+	path := filepath.Join(t.TempDir(), "sawtooth.raw")
+	if err = audio.SaveRaw(path); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Fatalf("saved data must be identical to %v", filename)
+	}
+
+	if _, err = OpenRaw(filename, 44100, 32, 1); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if _, err = OpenRaw(filename, 44100, 0, 1); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
+
+func TestRaw(t *testing.T) {
+	audio, err := NewFromInt16s([]int16{0x0102, -2, 0x0304, 0x7fff}, 44100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		format   RawFormat
+		expected []byte
+	}{
+		{RawFormat{BitsPerSample: 16}, audio.S16()},
+		{RawFormat{BitsPerSample: 16, ByteOrder: binary.BigEndian}, []byte{0x01, 0x02, 0xff, 0xfe, 0x03, 0x04, 0x7f, 0xff}},
+		{RawFormat{BitsPerSample: 16, ByteOrder: binary.LittleEndian, Planar: true}, []byte{0x02, 0x01, 0x04, 0x03, 0xfe, 0xff, 0xff, 0x7f}},
+		{RawFormat{BitsPerSample: 24, ByteOrder: binary.BigEndian, Planar: true}, []byte{0x01, 0x02, 0x00, 0x03, 0x04, 0x00, 0xff, 0xfe, 0x00, 0x7f, 0xff, 0x00}},
+		{RawFormat{BitsPerSample: 8}, audio.S8()},
+		{RawFormat{BitsPerSample: 32}, audio.S32()},
+	}
+	for i, test := range tests {
+		actual, err := audio.Raw(test.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(test.expected, actual) {
+			t.Fatalf("[%v] expected: %v actual: %v", i, test.expected, actual)
+		}
+	}
+
+	if _, err = audio.Raw(RawFormat{BitsPerSample: 12}); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if _, err = (&File{}).Raw(RawFormat{BitsPerSample: 16}); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
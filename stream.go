@@ -0,0 +1,163 @@
+package wav
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// ReadFromContext reads WAV formatted audio from r and returns it as *File.
+// The context is checked while copying the audio samples, and the decoding is aborted
+// with the context error when ctx is canceled. It prevents runaway decoding of huge untrusted streams.
+// The chunks which follow the data chunk, such as id3 chunk, are read until r reaches EOF.
+// Same as Decode, the stream which ends in the middle of the data chunk returns the partial audio,
+// and DeclaredVsActual reports how many bytes are missing.
+func ReadFromContext(ctx context.Context, r io.Reader) (*File, error) {
+	const chunkSize = 8 * 1024
+
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, ErrNotRIFF
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, ErrNotRIFF
+	}
+
+	audio := &File{}
+	hasFmt := false
+	hasData := false
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			if hasData {
+				// The chunks which follow the data chunk are optional, same as Unmarshal.
+				return audio, nil
+			}
+			return nil, ErrDataChunkNotFound
+		}
+		id := string(chunk[0:4])
+		size := binary.LittleEndian.Uint32(chunk[4:8])
+
+		var err error
+		switch {
+		case id == "fmt " && !hasData:
+			// Check the size before allocation, it comes from the untrusted stream.
+			if !(size == 16 || size == 18 || size == 40) {
+				return nil, fmt.Errorf("%w: size '%v'", ErrInvalidFmtChunk, size)
+			}
+			body := make([]byte, size)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return nil, ErrTruncatedData
+			}
+			if err := audio.parseFmt(bytes.NewReader(body), size); err != nil {
+				return nil, err
+			}
+			hasFmt = true
+		case id == "data" && !hasData && hasFmt:
+			buf := new(bytes.Buffer)
+
+			for remaining := int64(size); remaining > 0; remaining -= chunkSize {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				n := int64(chunkSize)
+				if remaining < n {
+					n = remaining
+				}
+				if _, err = io.CopyN(buf, r, n); err != nil {
+					break
+				}
+			}
+			audio.data = buf.Bytes()
+			audio.length = uint32(len(audio.data))
+			audio.missing = size - audio.length
+			hasData = true
+			if err == nil {
+				_, err = io.CopyN(ioutil.Discard, r, int64(size%2))
+			}
+		case id == "data" && !hasData:
+			return nil, fmt.Errorf("%w: fmt chunk not found", ErrInvalidFmtChunk)
+		case id == "id3 " || id == "ID3 " || id == "bext" || id == "cue " || id == "LIST":
+			// The buffer grows as the body arrives, so that the declared size is never allocated up front.
+			body := new(bytes.Buffer)
+			if _, err = io.CopyN(body, r, int64(size)); err == nil {
+				_, err = io.CopyN(ioutil.Discard, r, int64(size%2))
+			}
+			chunk := &rawChunk{id: id, data: body.Bytes()}
+			switch id {
+			case "bext":
+				audio.bext = chunk
+			case "cue ":
+				audio.cue = chunk
+			case "LIST":
+				audio.lists = append(audio.lists, chunk)
+			default:
+				audio.id3 = chunk
+			}
+		default:
+			_, err = io.CopyN(ioutil.Discard, r, int64(size)+int64(size%2))
+		}
+		if err != nil {
+			if hasData {
+				return audio, nil
+			}
+			return nil, ErrDataChunkNotFound
+		}
+	}
+}
+
+// DecodeReader reads WAV formatted audio from r and returns it as *File.
+// It is the streaming counterpart of Decode.
+func DecodeReader(r io.Reader) (*File, error) {
+	return ReadFromContext(context.Background(), r)
+}
+
+// StreamS16 writes audio samples into w as 16 bit signed integer in the same way as S16.
+// The samples are converted and written in small chunks, so that the whole output is never held in memory.
+func (v *File) StreamS16(w io.Writer) error {
+	return v.stream(w, (*File).S16)
+}
+
+// StreamS24 writes audio samples into w as 24 bit signed integer in the same way as S24.
+func (v *File) StreamS24(w io.Writer) error {
+	return v.stream(w, (*File).S24)
+}
+
+// StreamS32 writes audio samples into w as 32 bit signed integer in the same way as S32.
+func (v *File) StreamS32(w io.Writer) error {
+	return v.stream(w, (*File).S32)
+}
+
+// stream converts every chunk of the audio with convert and writes the result into w.
+func (v *File) stream(w io.Writer, convert func(*File) []byte) error {
+	const chunkFrames = 8 * 1024
+
+	if err := v.checkBlockAlign(); err != nil {
+		return err
+	}
+
+	size := chunkFrames * v.BlockAlign()
+	for offset := 0; offset < len(v.data); offset += size {
+		end := offset + size
+		if end > len(v.data) {
+			end = len(v.data)
+		}
+
+		chunk := v.empty()
+		chunk.data = v.data[offset:end]
+		chunk.length = uint32(end - offset)
+		if _, err := w.Write(convert(chunk)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,216 @@
+package wav
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// cancelReader cancels the context after reading limit bytes.
+type cancelReader struct {
+	r      *bytes.Reader
+	read   int
+	limit  int
+	cancel context.CancelFunc
+}
+
+func (c *cancelReader) Read(p []byte) (int, error) {
+	if len(p) > 1024 {
+		p = p[:1024]
+	}
+	n, err := c.r.Read(p)
+	c.read += n
+	if c.read >= c.limit {
+		c.cancel()
+	}
+	return n, err
+}
+
+func TestReadFromContext(t *testing.T) {
+	var audio *File
+	var file []byte
+	var err error
+
+	if file, err = ioutil.ReadFile("./testdata/sawtooth.wav"); err != nil {
+		t.Fatal(err)
+	}
+	if audio, err = ReadFromContext(context.Background(), bytes.NewReader(file)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &File{}
+	if err = Unmarshal(file, expected); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected.Bytes(), audio.Bytes()) {
+		t.Fatalf("expected: %v actual: %v", expected.Length(), audio.Length())
+	}
+	if expected.String() != audio.String() {
+		t.Fatalf("expected: %v actual: %v", expected, audio)
+	}
+	return
+}
+
+func TestReadFromContext_Cancel(t *testing.T) {
+	file, err := ioutil.ReadFile("./testdata/sawtooth.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := &cancelReader{r: bytes.NewReader(file), limit: 1024, cancel: cancel}
+
+	if _, err = ReadFromContext(ctx, r); err != context.Canceled {
+		t.Fatalf("expected: %v actual: %v", context.Canceled, err)
+	}
+	if r.read >= len(file) {
+		t.Fatalf("decoding must be aborted before reading the whole stream")
+	}
+	return
+}
+
+func TestDecodeReader(t *testing.T) {
+	file, err := ioutil.ReadFile("./testdata/sawtooth.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	audio, err := DecodeReader(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected.Bytes(), audio.Bytes()) || expected.String() != audio.String() {
+		t.Fatalf("expected: %v actual: %v", expected, audio)
+	}
+
+	if _, err = DecodeReader(bytes.NewReader(file[:8])); !errors.Is(err, ErrNotRIFF) {
+		t.Fatalf("expected: %v actual: %v", ErrNotRIFF, err)
+	}
+	return
+}
+
+func TestDecodeReader_Untrusted(t *testing.T) {
+	file, err := ioutil.ReadFile("./testdata/44100Hz-16bit-1ch-truncated.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The partial audio is returned in the same way as Decode.
+	audio, err := DecodeReader(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if declared, actual := audio.DeclaredVsActual(); declared != 100 || actual != 20 || audio.Length() != 20 {
+		t.Fatalf("expected: 100 20 actual: %v %v", declared, actual)
+	}
+	decoded, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.Bytes(), audio.Bytes()) {
+		t.Fatalf("expected: %v actual: %v", decoded.Bytes(), audio.Bytes())
+	}
+
+	// fmt chunk which claims 4 GB must be rejected before allocation.
+	header := []byte("RIFF\x00\x00\x00\x00WAVEfmt \xff\xff\xff\xff")
+	if _, err = DecodeReader(bytes.NewReader(header)); !errors.Is(err, ErrInvalidFmtChunk) {
+		t.Fatalf("expected: %v actual: %v", ErrInvalidFmtChunk, err)
+	}
+	return
+}
+
+func TestStreamS16(t *testing.T) {
+	// The audio is longer than a chunk, and the last chunk is partial.
+	audio, err := NewWave(Sawtooth, 441, 500*time.Millisecond, 44100, 24, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		stream   func(io.Writer) error
+		expected []byte
+	}{
+		{audio.StreamS16, audio.S16()},
+		{audio.StreamS24, audio.S24()},
+		{audio.StreamS32, audio.S32()},
+	}
+	for i, test := range tests {
+		buf := new(bytes.Buffer)
+		if err = test.stream(buf); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(test.expected, buf.Bytes()) {
+			t.Fatalf("[%v] streamed bytes must be same as converted bytes", i)
+		}
+	}
+
+	w := &failWriter{}
+	if err = audio.StreamS16(w); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+
+	file, err := ioutil.ReadFile("./testdata/44100Hz-16bit-2ch-zeroalign.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audio, err = Decode(file); err != nil {
+		t.Fatal(err)
+	}
+	if err = audio.StreamS16(ioutil.Discard); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
+
+type failWriter struct{}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+// maxWriter discards the written bytes and records the largest write.
+type maxWriter struct {
+	max int
+}
+
+func (w *maxWriter) Write(p []byte) (int, error) {
+	if len(p) > w.max {
+		w.max = len(p)
+	}
+	return len(p), nil
+}
+
+// The benchmarks report the largest output buffer which is held at once as peak-B/op.
+func BenchmarkS16(b *testing.B) {
+	audio, _ := New(44100, 24, 2)
+	audio.Write(make([]byte, 3*1000000))
+	w := &maxWriter{}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		w.Write(audio.S16())
+	}
+	b.ReportMetric(float64(w.max), "peak-B/op")
+}
+
+func BenchmarkStreamS16(b *testing.B) {
+	audio, _ := New(44100, 24, 2)
+	audio.Write(make([]byte, 3*1000000))
+	w := &maxWriter{}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		audio.StreamS16(w)
+	}
+	b.ReportMetric(float64(w.max), "peak-B/op")
+}
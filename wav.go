@@ -3,33 +3,73 @@ package wav
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"time"
 )
 
 const (
 	WAVE_FORMAT_PCM        = 0x1
+	WAVE_FORMAT_IEEE_FLOAT = 0x3
 	WAVE_FORMAT_EXTENSIBLE = 0xFFFE
 )
 
+var (
+	// ErrNotRIFF is returned when the stream does not start with RIFF WAVE header.
+	ErrNotRIFF = errors.New("wav: not a RIFF WAVE stream")
+	// ErrInvalidFormatTag is returned when the format tag or the subformat is not supported.
+	ErrInvalidFormatTag = errors.New("wav: invalid format tag")
+	// ErrInvalidFmtChunk is returned when the fmt chunk is malformed.
+	ErrInvalidFmtChunk = errors.New("wav: invalid fmt chunk")
+	// ErrTruncatedData is returned when the stream ends in the middle of the headers.
+	ErrTruncatedData = errors.New("wav: truncated data")
+	// ErrDataChunkNotFound is returned when the stream has no data chunk.
+	ErrDataChunkNotFound = errors.New("wav: data chunk not found")
+	// ErrDataTooLarge is returned when the data chunk exceeds the limit.
+	ErrDataTooLarge = errors.New("wav: data chunk too large")
+)
+
+// subFormatGUID is the KSDATAFORMAT_SUBTYPE GUID for WAVE_FORMAT_EXTENSIBLE.
+// The first two bytes are replaced with the format code such as WAVE_FORMAT_PCM.
+var subFormatGUID = [16]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71}
+
+// rawChunk represents the chunk which is preserved as is.
+type rawChunk struct {
+	id   string
+	data []byte
+}
+
 // File represents WAV audio file.
 type File struct {
 	formatTag      uint16
+	subFormat      uint16
 	channels       uint16
+	channelMask    uint32
+	extension      []byte
+	id3            *rawChunk
+	bext           *rawChunk
+	cue            *rawChunk   // not carried over by empty, since the sample offsets depend on the operation
+	lists          []*rawChunk // LIST chunks such as INFO and adtl
 	samplesPerSec  uint32
 	avgBytesPerSec uint32
 	blockAlign     uint16
 	bitsPerSample  uint16
 	length         uint32
+	missing        uint32 // bytes of the data chunk which are declared but missing in the truncated stream
 	data           []byte
 	offset         int
 }
 
-// Duration returns playback time in second.
+// Duration returns playback time.
 func (v *File) Duration() time.Duration {
-	return time.Duration(v.Length()/v.BlockAlign()) * time.Second
+	if v.samplesPerSec == 0 {
+		return 0
+	}
+	return time.Duration(int64(v.Frames()) * int64(time.Second) / int64(v.samplesPerSec))
 }
 
 // FormatTag returns either
@@ -39,17 +79,116 @@ func (v *File) FormatTag() uint16 {
 	return v.formatTag
 }
 
+// Format represents the format of the audio.
+type Format struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+	FormatTag     uint16
+}
+
+// Format returns the format of the audio.
+func (v *File) Format() Format {
+	return Format{
+		SampleRate:    v.SamplesPerSec(),
+		Channels:      v.Channels(),
+		BitsPerSample: v.BitsPerSample(),
+		FormatTag:     v.FormatTag(),
+	}
+}
+
+// SubFormat returns the effective format of the samples, either
+// 0x1 (WAVE_FORMAT_PCM) or
+// 0x3 (WAVE_FORMAT_IEEE_FLOAT).
+// For WAVE_FORMAT_EXTENSIBLE, it is derived from the subformat GUID.
+func (v *File) SubFormat() uint16 {
+	if v.subFormat == 0 {
+		return WAVE_FORMAT_PCM
+	}
+	return v.subFormat
+}
+
 // Channels returns number of channels.
 func (v *File) Channels() int {
 	return int(v.channels)
 }
 
+// speakerNames are the names of the speaker positions in the order of the channel mask bits.
+var speakerNames = []string{
+	"FL", "FR", "FC", "LFE", "BL", "BR", "FLC", "FRC", "BC",
+	"SL", "SR", "TC", "TFL", "TFC", "TFR", "TBL", "TBC", "TBR",
+}
+
+// ChannelMask returns the channel mask which assigns channels to speaker positions.
+// If the mask is not set, the conventional mask for the number of channels is returned.
+func (v *File) ChannelMask() uint32 {
+	if v.channelMask == 0 {
+		return getChannelMask(v.channels)
+	}
+	return v.channelMask
+}
+
+// SetChannelMask sets the channel mask which is written by Marshal for WAVE_FORMAT_EXTENSIBLE.
+func (v *File) SetChannelMask(mask uint32) {
+	v.channelMask = mask
+	if len(v.extension) >= 6 {
+		binary.LittleEndian.PutUint32(v.extension[2:6], mask)
+	}
+}
+
+// FormatExtension returns the raw extension of the fmt chunk which follows cbSize field,
+// such as valid bits per sample, channel mask and subformat GUID of WAVE_FORMAT_EXTENSIBLE.
+// It returns an empty slice if the audio has no extension.
+// Only the 22 bytes extension of WAVE_FORMAT_EXTENSIBLE whose subformat is PCM or IEEE float is kept,
+// and Marshal writes it back verbatim. The vendor-specific subformats cannot be passed through,
+// since Unmarshal rejects them with ErrInvalidFormatTag.
+func (v *File) FormatExtension() []byte {
+	extension := make([]byte, len(v.extension))
+	copy(extension, v.extension)
+
+	return extension
+}
+
+// SpeakerLayout returns the names of the speaker positions which are active in the channel mask.
+// For example, 5.1 surround audio returns ["FL" "FR" "FC" "LFE" "SL" "SR"].
+func (v *File) SpeakerLayout() []string {
+	mask := v.ChannelMask()
+	layout := []string{}
+
+	for i, name := range speakerNames {
+		if mask&(1<<uint(i)) != 0 {
+			layout = append(layout, name)
+		}
+	}
+
+	return layout
+}
+
+// ChannelOrder returns the speaker name of each interleaved channel.
+// The channels are assigned to the speaker positions in the order of the channel mask bits,
+// for example the plain stereo audio returns ["FL" "FR"]. The channels which exceed the channel mask
+// are not assigned to any speaker, and their names are empty.
+func (v *File) ChannelOrder() []string {
+	order := make([]string, v.Channels())
+	copy(order, v.SpeakerLayout())
+
+	return order
+}
+
 // SamplesPerSec returns number of samples per second.
 // For example, CD quality audio is encoded as 44100 samples per second.
 func (v *File) SamplesPerSec() int {
 	return int(v.samplesPerSec)
 }
 
+// SetSampleRate changes number of samples per second and recomputes average bytes per second.
+// The samples are left untouched, so the audio is played back at different speed and pitch.
+// It is useful to fix the audio which is labeled with wrong sample rate.
+func (v *File) SetSampleRate(rate int) {
+	v.samplesPerSec = uint32(rate)
+	v.avgBytesPerSec = v.samplesPerSec * uint32(v.blockAlign)
+}
+
 // Samples returns number of the samples that the audio contains.
 // For example, 10 seconds of the stereo audio which is encoded 16 bit / 44.1 kHz contains 882000 samples.
 func (v *File) Samples() int {
@@ -61,6 +200,13 @@ func (v *File) AvgBytesPerSec() int {
 	return int(v.avgBytesPerSec)
 }
 
+// BitRate returns bits per second.
+// Since PCM audio is uncompressed, the bit rate is always AvgBytesPerSec() * 8.
+// For example, CD quality audio is 1411200 bps.
+func (v *File) BitRate() int {
+	return v.AvgBytesPerSec() * 8
+}
+
 // BlockAlign returns block align size in byte.
 func (v *File) BlockAlign() int {
 	return int(v.blockAlign)
@@ -77,45 +223,129 @@ func (v *File) Length() int {
 	return int(v.length)
 }
 
-// Read reads audio samples byte by byte.
+// RepairHeader recomputes block align and average bytes per second from the number of channels,
+// bits per sample and samples per second. It corrects the inconsistent header which is written by some encoders.
+func (v *File) RepairHeader() {
+	v.blockAlign = v.channels * v.bitsPerSample / 8
+	v.avgBytesPerSec = v.samplesPerSec * uint32(v.blockAlign)
+}
+
+// checkBlockAlign returns an error if the block align is inconsistent with the number of channels and bits per sample.
+// The loops which step frame by frame never advance when the block align is 0.
+func (v *File) checkBlockAlign() error {
+	if v.BlockAlign() == 0 || v.BlockAlign() != v.Channels()*v.BitsPerSample()/8 {
+		return fmt.Errorf("wav: invalid block align (%v)", v.BlockAlign())
+	}
+	return nil
+}
+
+// DeclaredVsActual returns the length of the data chunk in bytes which is declared in the header,
+// and the length of the audio samples which are actually parsed. The actual length is shorter
+// than the declared length when the stream is truncated. Length and the other methods report the actual length.
+func (v *File) DeclaredVsActual() (declared, actual int) {
+	return int(v.length) + int(v.missing), len(v.data)
+}
+
+// Frames returns number of the frames, each of which holds one sample per channel.
+// For example, 10 seconds of the stereo audio which is encoded 16 bit / 44.1 kHz contains 441000 frames.
+func (v *File) Frames() int {
+	if v.blockAlign == 0 {
+		return 0
+	}
+	return v.Length() / v.BlockAlign()
+}
+
+// framesOf returns number of the frames which is played in d, rounded to the nearest frame.
+func (v *File) framesOf(d time.Duration) int {
+	return int(math.Round(d.Seconds() * float64(v.samplesPerSec)))
+}
+
+// empty returns a new File which has the same format as v and contains no samples.
+func (v *File) empty() *File {
+	return &File{
+		formatTag:      v.formatTag,
+		subFormat:      v.subFormat,
+		channels:       v.channels,
+		channelMask:    v.channelMask,
+		extension:      append([]byte(nil), v.extension...),
+		id3:            v.id3,
+		bext:           v.bext,
+		lists:          v.lists,
+		samplesPerSec:  v.samplesPerSec,
+		avgBytesPerSec: v.avgBytesPerSec,
+		blockAlign:     v.blockAlign,
+		bitsPerSample:  v.bitsPerSample,
+		data:           []byte{},
+	}
+}
+
+// Read reads audio samples.
+// It returns io.EOF when all samples have been read.
 func (v *File) Read(p []byte) (int, error) {
 	length := v.Length()
-	size := len(p)
-
-	for i := 0; i < size; i++ {
-		if v.offset >= length {
-			return i, io.EOF
+	if length > len(v.data) {
+		length = len(v.data)
+	}
+	if v.offset >= length {
+		if len(p) == 0 {
+			return 0, nil
 		}
-		p[i] = v.data[v.offset]
-		v.offset++
+		return 0, io.EOF
 	}
 
-	return size, nil
+	n := copy(p, v.data[v.offset:length])
+	v.offset += n
+
+	return n, nil
 }
 
-// Write writes audio samples byte by byte.
+// Write appends audio samples.
 func (v *File) Write(b []byte) (n int, err error) {
-	size := len(b)
+	v.data = append(v.data, b...)
+	v.length += uint32(len(b))
 
-	for n = 0; n < size; n++ {
-		v.data = append(v.data, b[n])
-	}
-	v.length += uint32(size)
-	return
+	return len(b), nil
 }
 
 // Bytes returns audio samples as byte slice.
+// The returned slice is the live data of v, so modifying it changes the audio.
+// Use BytesCopy to get an independent copy.
 func (v *File) Bytes() []byte {
 	return v.data
 }
 
-// String returns textual representation of audio.
+// BytesCopy returns a copy of audio samples as byte slice.
+func (v *File) BytesCopy() []byte {
+	b := make([]byte, len(v.data))
+	copy(b, v.data)
+
+	return b
+}
+
+// String returns textual representation of audio such as "44100 Hz / 16 bit / 2ch / PCM / 3.50s".
+// Note that the representation is meant for humans and may change, do not parse it.
 func (v *File) String() string {
-	return fmt.Sprintf("%v kHz / %v bit %v channel(s)", v.SamplesPerSec(), v.BitsPerSample(), v.Channels())
+	return fmt.Sprintf("%v Hz / %v bit / %vch / %v / %.2fs", v.SamplesPerSec(), v.BitsPerSample(), v.Channels(), v.formatName(), v.Duration().Seconds())
+}
+
+// formatName returns the name of the format derived from the format tag.
+func (v *File) formatName() string {
+	name := "PCM"
+	if v.SubFormat() == WAVE_FORMAT_IEEE_FLOAT {
+		name = "IEEE float"
+	}
+	if v.formatTag == WAVE_FORMAT_EXTENSIBLE {
+		name = "extensible " + name
+	}
+	return name
 }
 
 // Float64s returns audio samples as slice of float64.
 func (v *File) Float64s() []float64 {
+	if v.SubFormat() == WAVE_FORMAT_IEEE_FLOAT {
+		return v.fromFloatToF64()
+	}
+
 	const scale = 1 << 31
 	samples := v.Samples()
 	s32 := v.Int32s()
@@ -128,34 +358,244 @@ func (v *File) Float64s() []float64 {
 	return f64
 }
 
+// CloseTo reports whether v and other have the same format and length,
+// and every normalized sample differs by no more than epsilon.
+// It is useful to compare audio which is converted through float64 and back.
+func (v *File) CloseTo(other *File, epsilon float64) bool {
+	if other == nil {
+		return false
+	}
+	if v.formatTag != other.formatTag ||
+		v.SubFormat() != other.SubFormat() ||
+		v.channels != other.channels ||
+		v.samplesPerSec != other.samplesPerSec ||
+		v.bitsPerSample != other.bitsPerSample ||
+		v.length != other.length {
+		return false
+	}
+
+	a := v.Float64s()
+	b := other.Float64s()
+
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > epsilon {
+			return false
+		}
+	}
+
+	return true
+}
+
+// setFloat64s replaces audio samples with f64 which is normalized to -1.0 to 1.0.
+// The samples are quantized to the bit depth of the audio and the out of range values are clamped.
+func (v *File) setFloat64s(f64 []float64) {
+	bits := v.BitsPerSample()
+	width := bits / 8
+	scale := math.Ldexp(1, bits-1)
+	data := make([]byte, len(f64)*width)
+
+	if v.SubFormat() == WAVE_FORMAT_IEEE_FLOAT {
+		for i, f := range f64 {
+			if bits == 64 {
+				binary.LittleEndian.PutUint64(data[i*8:], math.Float64bits(f))
+			} else {
+				binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(float32(f)))
+			}
+		}
+		v.data = data
+		v.length = uint32(len(data))
+		return
+	}
+
+	for i, f := range f64 {
+		s := math.Round(f * scale)
+		if s > scale-1 {
+			s = scale - 1
+		}
+		if s < -scale {
+			s = -scale
+		}
+		if bits == 8 {
+			data[i] = byte(int8(s)) + 128
+			continue
+		}
+		for j := 0; j < width; j++ {
+			data[i*width+j] = byte(int64(s) >> uint(8*j))
+		}
+	}
+
+	v.data = data
+	v.length = uint32(len(data))
+}
+
+// Float64sRange returns the normalized audio samples between start and end in the same way as Float64s.
+// The range is snapped to frame boundaries, and only the samples in the range are decoded.
+func (v *File) Float64sRange(start, end time.Duration) ([]float64, error) {
+	first, last, err := v.frameRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	stride := v.BlockAlign()
+	window := v.empty()
+	window.data = v.data[first*stride : last*stride]
+	window.length = uint32(len(window.data))
+
+	return window.Float64s(), nil
+}
+
+// FloatFrames returns audio samples as slice of frames.
+// Each frame holds one float64 sample per channel normalized in the same way as Float64s.
+func (v *File) FloatFrames() [][]float64 {
+	channels := v.Channels()
+	frames := v.Frames()
+	f64 := v.Float64s()
+	result := make([][]float64, frames)
+
+	for i := range result {
+		result[i] = f64[i*channels : (i+1)*channels : (i+1)*channels]
+	}
+
+	return result
+}
+
+// PlanarInt16 returns audio samples as non-interleaved 16 bit signed integer, one slice per channel.
+// The samples which are encoded with higher bit depth are truncated to 16 bit.
+func (v *File) PlanarInt16() [][]int16 {
+	channels := v.Channels()
+	frames := v.Frames()
+	s32 := v.Int32s()
+	planes := make([][]int16, channels)
+
+	for c := range planes {
+		planes[c] = make([]int16, frames)
+		for i := 0; i < frames; i++ {
+			planes[c][i] = int16(s32[i*channels+c] >> 16)
+		}
+	}
+
+	return planes
+}
+
+// Float32s returns audio samples as slice of float32.
+// The samples are normalized in the same way as Float64s.
+func (v *File) Float32s() []float32 {
+	samples := v.Samples()
+	f32 := make([]float32, samples)
+
+	if v.SubFormat() == WAVE_FORMAT_IEEE_FLOAT && v.BitsPerSample() == 32 {
+		for i := 0; i < samples && i < len(v.data)/4; i++ {
+			f32[i] = math.Float32frombits(binary.LittleEndian.Uint32(v.data[i*4:]))
+		}
+		return f32
+	}
+
+	for i, f := range v.Float64s() {
+		f32[i] = float32(f)
+	}
+
+	return f32
+}
+
+// fromFloatToF64 decodes IEEE float samples.
+func (v *File) fromFloatToF64() []float64 {
+	samples := v.Samples()
+	f64 := make([]float64, samples)
+
+	width := v.BitsPerSample() / 8
+	if width == 0 {
+		return f64
+	}
+	for i := 0; i < samples && i < len(v.data)/width; i++ {
+		switch v.BitsPerSample() {
+		case 32:
+			f64[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(v.data[i*4:])))
+		case 64:
+			f64[i] = math.Float64frombits(binary.LittleEndian.Uint64(v.data[i*8:]))
+		}
+	}
+
+	return f64
+}
+
 // Int32s returns audio samples as slice of int32.
 func (v *File) Int32s() []int32 {
-	var s32 []byte
+	if v.SubFormat() == WAVE_FORMAT_IEEE_FLOAT {
+		const scale = 1 << 31
+		f64 := v.fromFloatToF64()
+		i32 := make([]int32, len(f64))
+
+		for i, f := range f64 {
+			f = math.Round(f * scale)
+			if f > math.MaxInt32 {
+				f = math.MaxInt32
+			}
+			if f < math.MinInt32 {
+				f = math.MinInt32
+			}
+			i32[i] = int32(f)
+		}
+
+		return i32
+	}
+
+	i32 := make([]int32, v.Samples())
+	data := v.data
 
 	switch v.BitsPerSample() {
 	case 8:
-		s32 = v.fromU8ToS32()
+		for i := range i32 {
+			if i >= len(data) {
+				break
+			}
+			i32[i] = int32(int8(data[i]-128)) << 24
+		}
 	case 16:
-		s32 = v.fromS16ToS32()
+		for i := range i32 {
+			if i*2+1 >= len(data) {
+				break
+			}
+			i32[i] = int32(uint32(data[i*2])<<16 | uint32(data[i*2+1])<<24)
+		}
 	case 24:
-		s32 = v.fromS24ToS32()
+		for i := range i32 {
+			if i*3+2 >= len(data) {
+				break
+			}
+			i32[i] = int32(uint32(data[i*3])<<8 | uint32(data[i*3+1])<<16 | uint32(data[i*3+2])<<24)
+		}
 	case 32:
-		s32 = v.data
+		for i := range i32 {
+			if i*4+3 >= len(data) {
+				break
+			}
+			i32[i] = int32(binary.LittleEndian.Uint32(data[i*4:]))
+		}
 	default:
 		return []int32{}
 	}
 
-	i32 := make([]int32, v.Samples())
-	binary.Read(bytes.NewBuffer(s32), binary.LittleEndian, &i32)
-
 	return i32
 }
 
+// Int16s returns audio samples as slice of int16.
+// The samples which are encoded with higher bit depth are truncated to 16 bit.
+func (v *File) Int16s() []int16 {
+	s32 := v.Int32s()
+	i16 := make([]int16, len(s32))
+
+	for i, s := range s32 {
+		i16[i] = int16(s >> 16)
+	}
+
+	return i16
+}
+
 // S8 returns audio samples as byte slice which is encoded 8 bit signed integer.
 func (v *File) S8() []byte {
 	switch v.BitsPerSample() {
 	case 8:
-	// return v.fromU8ToS8()
+		return v.fromU8ToS8()
 	case 16:
 		return v.fromS16ToS8()
 	case 24:
@@ -247,6 +687,18 @@ func (v *File) fromS8ToS32() []byte {
 	return s32
 }
 
+func (v *File) fromU8ToS8() []byte {
+	length := v.Length()
+	data := v.data
+	s8 := make([]byte, length)
+
+	for i := 0; i < length; i++ {
+		s8[i] = data[i] - 128
+	}
+
+	return s8
+}
+
 func (v *File) fromU8ToS32() []byte {
 	length := v.Length()
 	data := v.data
@@ -376,57 +828,381 @@ func (v *File) fromS32ToS24() []byte {
 }
 
 // Unmarshal parses WAV formatted audio and store data into *File.
+// The size of the audio samples is not limited, use UnmarshalLimit to parse untrusted stream.
 func Unmarshal(stream []byte, audio *File) (err error) {
+	return unmarshal(stream, audio, false, -1)
+}
+
+// Decode parses WAV formatted audio and returns it as *File.
+// It is same as Unmarshal except that the File is allocated by Decode.
+func Decode(stream []byte) (*File, error) {
+	audio := &File{}
+	if err := Unmarshal(stream, audio); err != nil {
+		return nil, err
+	}
+	return audio, nil
+}
+
+// UnmarshalLimit is same as Unmarshal except that it rejects the stream
+// whose declared size of the audio samples exceeds maxBytes.
+func UnmarshalLimit(stream []byte, audio *File, maxBytes int) (err error) {
+	return unmarshal(stream, audio, false, maxBytes)
+}
+
+// UnmarshalShared is same as Unmarshal except that the audio samples share the memory with stream
+// instead of being copied. This halves the memory usage for large audio.
+// It is unsafe to modify stream after calling UnmarshalShared, the changes are visible through *File and vice versa.
+func UnmarshalShared(stream []byte, audio *File) (err error) {
+	return unmarshal(stream, audio, true, -1)
+}
+
+// unmarshal parses stream. If maxBytes is not negative, it limits the size of the audio samples.
+func unmarshal(stream []byte, audio *File, shared bool, maxBytes int) (err error) {
 	if audio == nil {
 		err = fmt.Errorf("error: nil WAVE stream")
 		return
 	}
 
+	if len(stream) < 12 || string(stream[0:4]) != "RIFF" || string(stream[8:12]) != "WAVE" {
+		err = ErrNotRIFF
+		return
+	}
+	if len(stream) < 36 {
+		err = ErrTruncatedData
+		return
+	}
+
 	reader := bytes.NewReader(stream)
-	binary.Read(io.NewSectionReader(reader, 20, 2), binary.LittleEndian, &audio.formatTag)
 
-	if !(audio.formatTag == WAVE_FORMAT_PCM || audio.formatTag == WAVE_FORMAT_EXTENSIBLE) {
-		err = fmt.Errorf("error: invalid format tag '%v'", audio.formatTag)
+	// Walk through the chunks, such as fmt, fact, bext, data, cue, LIST and id3 chunk.
+	// The fmt chunk does not always come first, some encoders write JUNK or LIST chunk before it.
+	fmtOffset := int64(-1)
+	dataOffset := int64(-1)
+	var fmtSize uint32
+	var lists []*rawChunk
+	offset := int64(12)
+	for {
+		var id [4]byte
+		var size uint32
+
+		if binary.Read(io.NewSectionReader(reader, offset, 4), binary.BigEndian, &id) != nil {
+			break
+		}
+		binary.Read(io.NewSectionReader(reader, offset+4, 4), binary.LittleEndian, &size)
+		offset += 8
+
+		switch string(id[:]) {
+		case "fmt ":
+			if fmtOffset >= 0 {
+				break
+			}
+			fmtOffset = offset
+			fmtSize = size
+		case "data":
+			if dataOffset >= 0 {
+				break
+			}
+			audio.length = size
+			dataOffset = offset
+		case "id3 ", "ID3 ":
+			body := new(bytes.Buffer)
+			io.Copy(body, io.NewSectionReader(reader, offset, int64(size)))
+			audio.id3 = &rawChunk{id: string(id[:]), data: body.Bytes()}
+		case "bext":
+			body := new(bytes.Buffer)
+			io.Copy(body, io.NewSectionReader(reader, offset, int64(size)))
+			audio.bext = &rawChunk{id: "bext", data: body.Bytes()}
+		case "cue ":
+			body := new(bytes.Buffer)
+			io.Copy(body, io.NewSectionReader(reader, offset, int64(size)))
+			audio.cue = &rawChunk{id: "cue ", data: body.Bytes()}
+		case "LIST":
+			body := new(bytes.Buffer)
+			io.Copy(body, io.NewSectionReader(reader, offset, int64(size)))
+			lists = append(lists, &rawChunk{id: "LIST", data: body.Bytes()})
+		}
+		offset += int64(size) + int64(size%2)
+	}
+	audio.lists = lists
+	if fmtOffset < 0 {
+		err = fmt.Errorf("%w: fmt chunk not found", ErrInvalidFmtChunk)
 		return
 	}
 
-	binary.Read(io.NewSectionReader(reader, 22, 2), binary.LittleEndian, &audio.channels)
-	binary.Read(io.NewSectionReader(reader, 24, 4), binary.LittleEndian, &audio.samplesPerSec)
-	binary.Read(io.NewSectionReader(reader, 28, 4), binary.LittleEndian, &audio.avgBytesPerSec)
-	binary.Read(io.NewSectionReader(reader, 32, 2), binary.LittleEndian, &audio.blockAlign)
-	binary.Read(io.NewSectionReader(reader, 34, 2), binary.LittleEndian, &audio.bitsPerSample)
+	// The fmt chunk is 16 (PCM), 18 (PCM with cbSize) or 40 (extensible) bytes long.
+	if err = audio.parseFmt(io.NewSectionReader(reader, fmtOffset, int64(fmtSize)), fmtSize); err != nil {
+		return
+	}
+	if int64(len(stream)) < fmtOffset+int64(fmtSize) {
+		err = ErrTruncatedData
+		return
+	}
+	if dataOffset < 0 {
+		err = ErrDataChunkNotFound
+		return
+	}
+	if maxBytes >= 0 && int64(audio.length) > int64(maxBytes) {
+		err = fmt.Errorf("%w: size '%v' exceeds the limit '%v'", ErrDataTooLarge, audio.length, maxBytes)
+		return
+	}
+	offset = dataOffset
 
-	if audio.formatTag == WAVE_FORMAT_PCM {
-		binary.Read(io.NewSectionReader(reader, 40, 4), binary.LittleEndian, &audio.length)
-	} else if audio.formatTag == WAVE_FORMAT_EXTENSIBLE {
-		binary.Read(io.NewSectionReader(reader, 76, 4), binary.LittleEndian, &audio.length)
+	if shared {
+		start := offset
+		if start > int64(len(stream)) {
+			start = int64(len(stream))
+		}
+		end := start + int64(audio.length)
+		if end > int64(len(stream)) {
+			end = int64(len(stream))
+		}
+		// Limit the capacity so that Write never overwrites the rest of stream.
+		audio.data = stream[start:end:end]
+	} else {
+		buf := new(bytes.Buffer)
+		io.Copy(buf, io.NewSectionReader(reader, offset, int64(audio.length)))
+		audio.data = buf.Bytes()
 	}
 
-	buf := new(bytes.Buffer)
-	if audio.formatTag == WAVE_FORMAT_PCM {
-		io.Copy(buf, io.NewSectionReader(reader, 44, int64(audio.length)))
-	} else if audio.formatTag == WAVE_FORMAT_EXTENSIBLE {
-		io.Copy(buf, io.NewSectionReader(reader, 80, int64(audio.length)))
+	// Keep the length in sync with the data, so that the truncated audio is handled as the shorter one.
+	audio.missing = audio.length - uint32(len(audio.data))
+	audio.length = uint32(len(audio.data))
+
+	return
+}
+
+// parseFmt parses the body of the fmt chunk whose size is fmtSize.
+func (v *File) parseFmt(r io.ReaderAt, fmtSize uint32) (err error) {
+	binary.Read(io.NewSectionReader(r, 0, 2), binary.LittleEndian, &v.formatTag)
+
+	if !(v.formatTag == WAVE_FORMAT_PCM || v.formatTag == WAVE_FORMAT_EXTENSIBLE) {
+		err = fmt.Errorf("%w '%v'", ErrInvalidFormatTag, v.formatTag)
+		return
+	}
+
+	binary.Read(io.NewSectionReader(r, 2, 2), binary.LittleEndian, &v.channels)
+	binary.Read(io.NewSectionReader(r, 4, 4), binary.LittleEndian, &v.samplesPerSec)
+	binary.Read(io.NewSectionReader(r, 8, 4), binary.LittleEndian, &v.avgBytesPerSec)
+	binary.Read(io.NewSectionReader(r, 12, 2), binary.LittleEndian, &v.blockAlign)
+	binary.Read(io.NewSectionReader(r, 14, 2), binary.LittleEndian, &v.bitsPerSample)
+
+	if !(fmtSize == 16 || fmtSize == 18 || fmtSize == 40) {
+		err = fmt.Errorf("%w: size '%v'", ErrInvalidFmtChunk, fmtSize)
+		return
+	}
+
+	v.subFormat = WAVE_FORMAT_PCM
+
+	if v.formatTag == WAVE_FORMAT_EXTENSIBLE && fmtSize == 40 {
+		var guid [16]byte
+		binary.Read(io.NewSectionReader(r, 24, 16), binary.BigEndian, &guid)
+
+		code := binary.LittleEndian.Uint16(guid[:2])
+		if !bytes.Equal(guid[2:], subFormatGUID[2:]) || !(code == WAVE_FORMAT_PCM || code == WAVE_FORMAT_IEEE_FLOAT) {
+			err = fmt.Errorf("%w: subformat GUID '%x'", ErrInvalidFormatTag, guid)
+			return
+		}
+		v.subFormat = code
+		binary.Read(io.NewSectionReader(r, 20, 4), binary.LittleEndian, &v.channelMask)
+
+		v.extension = make([]byte, 22)
+		r.ReadAt(v.extension, 18)
+	}
+	if v.subFormat == WAVE_FORMAT_IEEE_FLOAT && !(v.bitsPerSample == 32 || v.bitsPerSample == 64) {
+		err = fmt.Errorf("%w: bits per sample for IEEE float '%v'", ErrInvalidFmtChunk, v.bitsPerSample)
+		return
 	}
-	audio.data = buf.Bytes()
 
 	return
 }
 
+// MarshalSize returns the size of WAV formatted data in bytes which Marshal produces.
+// It returns 0 if the audio cannot be marshaled.
+func (v *File) MarshalSize() int {
+	size := uint64(v.length) + uint64(v.length%2)
+	for _, chunk := range append(v.leadingChunks(), v.trailingChunks()...) {
+		size += chunk.size()
+	}
+
+	switch v.formatTag {
+	case WAVE_FORMAT_PCM:
+		size += 44
+	case WAVE_FORMAT_EXTENSIBLE:
+		// The fmt chunk has 24 bytes of extension, which is followed by 12 bytes of fact chunk.
+		size += 80
+	default:
+		return 0
+	}
+	if size-8 > math.MaxUint32 {
+		return 0
+	}
+
+	return int(size)
+}
+
+// size returns the size of the whole chunk in bytes including the chunk header and the pad byte.
+func (c *rawChunk) size() uint64 {
+	return uint64(8 + len(c.data) + len(c.data)%2)
+}
+
+// writeTo writes the chunk into buf.
+func (c *rawChunk) writeTo(buf *bytes.Buffer) {
+	buf.WriteString(c.id)
+	binary.Write(buf, binary.LittleEndian, uint32(len(c.data)))
+	buf.Write(c.data)
+	if len(c.data)%2 == 1 {
+		buf.WriteByte(0)
+	}
+}
+
+// leadingChunks returns the preserved chunks which Marshal writes between the fmt chunk and the data chunk.
+func (v *File) leadingChunks() []*rawChunk {
+	chunks := []*rawChunk{}
+	if v.bext != nil {
+		chunks = append(chunks, v.bext)
+	}
+	return chunks
+}
+
+// trailingChunks returns the preserved chunks which Marshal writes after the data chunk.
+func (v *File) trailingChunks() []*rawChunk {
+	chunks := []*rawChunk{}
+	if v.cue != nil {
+		chunks = append(chunks, v.cue)
+	}
+	chunks = append(chunks, v.lists...)
+	if v.id3 != nil {
+		chunks = append(chunks, v.id3)
+	}
+	return chunks
+}
+
+// MarshalOptions controls how MarshalWith encodes audio.
+type MarshalOptions struct {
+	// WriteFactChunk writes the optional fact chunk for WAVE_FORMAT_EXTENSIBLE audio.
+	WriteFactChunk bool
+	// JunkSize is the size in bytes of JUNK chunk which is written before the data chunk.
+	// The zero filled JUNK chunk reserves space so that the headers can be rewritten in place later.
+	// No JUNK chunk is written if it is 0.
+	JunkSize int
+	// DataAlignment aligns the audio samples in the data chunk to the multiple of DataAlignment bytes
+	// from the beginning of the stream, for example 4096 for the memory mapped playback.
+	// The JUNK chunk is enlarged to fill the gap. It must be the power of two, and 0 disables the alignment.
+	DataAlignment int
+}
+
 // Marshal returns audio data as WAV formatted data.
+// It is same as MarshalWith with the fact chunk enabled.
 func Marshal(v *File) (stream []byte, err error) {
+	return MarshalWith(v, MarshalOptions{WriteFactChunk: true})
+}
+
+// MarshalWith returns audio data as WAV formatted data encoded with opts.
+func MarshalWith(v *File, opts MarshalOptions) (stream []byte, err error) {
 	buf := new(bytes.Buffer)
-	binary.Write(buf, binary.BigEndian, []byte("RIFF"))
+	if err = marshal(v, buf, opts); err != nil {
+		return
+	}
+	stream = buf.Bytes()
+
+	return
+}
+
+// MarshalTo writes audio data as WAV formatted data into buf.
+// The output is same as Marshal. buf is reset before writing, so that services which marshal
+// many files can reuse a buffer (e.g. taken from sync.Pool) across calls to reduce allocations.
+func MarshalTo(v *File, buf *bytes.Buffer) error {
+	buf.Reset()
+	if err := marshal(v, buf, MarshalOptions{WriteFactChunk: true}); err != nil {
+		buf.Reset()
+		return err
+	}
+	return nil
+}
+
+// DataURL returns the output of Marshal as base64 encoded data URL (data:audio/wav;base64,...),
+// which can be embedded in HTML or played with Web Audio API.
+func (v *File) DataURL() (string, error) {
+	stream, err := Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return "data:audio/wav;base64," + base64.StdEncoding.EncodeToString(stream), nil
+}
 
+func marshal(v *File, buf *bytes.Buffer, opts MarshalOptions) (err error) {
+	writeFactChunk := opts.WriteFactChunk && v.formatTag == WAVE_FORMAT_EXTENSIBLE
+
+	// Chunks must be word aligned, the data chunk with odd length is followed by a pad byte.
+	padding := v.length % 2
+
+	// The chunks which follow the data chunk.
+	trailing := v.trailingChunks()
+	trailingSize := uint64(0)
+	for _, chunk := range trailing {
+		trailingSize += chunk.size()
+	}
+
+	// The size of the chunks which follow RIFF header, that is the fmt chunk, the optional fact chunk, the leading chunks and the data chunk header.
+	var headerSize uint64
 	if v.formatTag == WAVE_FORMAT_PCM {
-		binary.Write(buf, binary.LittleEndian, uint32(v.length+36))
+		headerSize = 36
+	} else if v.formatTag == WAVE_FORMAT_EXTENSIBLE && writeFactChunk {
+		headerSize = 72
 	} else if v.formatTag == WAVE_FORMAT_EXTENSIBLE {
-		binary.Write(buf, binary.LittleEndian, uint32(v.length+72))
+		headerSize = 60
 	} else {
-		err = fmt.Errorf("error: invalid format tag")
+		err = fmt.Errorf("%w '%v'", ErrInvalidFormatTag, v.formatTag)
+		return
+	}
+
+	// The chunks which precede the data chunk, such as bext chunk.
+	leading := v.leadingChunks()
+	for _, chunk := range leading {
+		headerSize += chunk.size()
+	}
+
+	if opts.JunkSize < 0 {
+		err = fmt.Errorf("wav: invalid JUNK chunk size (%v)", opts.JunkSize)
+		return
+	}
+	if opts.DataAlignment < 0 || opts.DataAlignment&(opts.DataAlignment-1) != 0 {
+		err = fmt.Errorf("wav: data alignment must be the power of two (%v)", opts.DataAlignment)
 		return
 	}
 
+	// junk is the size of the body of JUNK chunk, and junkSize is the size of the whole JUNK chunk.
+	junk := opts.JunkSize
+	junkSize := uint64(0)
+	if junk > 0 {
+		junkSize = uint64(8 + junk + junk%2)
+	}
+	if align := uint64(opts.DataAlignment); align > 1 {
+		// The audio samples start after RIFF chunk ID, RIFF size and the headers.
+		start := 8 + headerSize
+		if gap := (start + junkSize) % align; gap != 0 {
+			junkSize += align - gap
+		}
+		// The smallest JUNK chunk is 8 bytes long.
+		for junkSize > 0 && junkSize < 8 {
+			junkSize += align
+		}
+		if junkSize > 0 {
+			junk = int(junkSize - 8)
+		}
+	}
+
+	// RIFF size is 32 bit, it must not wrap around for the files near 4 GB.
+	riffSize := uint64(v.length) + uint64(padding) + trailingSize + junkSize + headerSize
+	if riffSize > math.MaxUint32 {
+		err = fmt.Errorf("%w: RIFF size (%v bytes) exceeds 4 GB", ErrDataTooLarge, riffSize)
+		return
+	}
+
+	buf.Grow(int(riffSize) + 8)
+	binary.Write(buf, binary.BigEndian, []byte("RIFF"))
+	binary.Write(buf, binary.LittleEndian, uint32(riffSize))
+
 	binary.Write(buf, binary.BigEndian, []byte("WAVEfmt "))
 
 	if v.formatTag == WAVE_FORMAT_PCM {
@@ -442,24 +1218,44 @@ func Marshal(v *File) (stream []byte, err error) {
 	binary.Write(buf, binary.LittleEndian, v.blockAlign)
 	binary.Write(buf, binary.LittleEndian, v.bitsPerSample)
 
-	if v.formatTag == WAVE_FORMAT_EXTENSIBLE {
+	if v.formatTag == WAVE_FORMAT_EXTENSIBLE && len(v.extension) == 22 {
+		binary.Write(buf, binary.LittleEndian, uint16(22)) // cbSize
+		buf.Write(v.extension)
+	} else if v.formatTag == WAVE_FORMAT_EXTENSIBLE {
 		binary.Write(buf, binary.LittleEndian, uint16(22)) // cbSize
 		// validBitsPerSample
 		binary.Write(buf, binary.LittleEndian, v.bitsPerSample)
 		// channelMask
-		binary.Write(buf, binary.LittleEndian, uint32(getChannelMask(v.channels)))
+		binary.Write(buf, binary.LittleEndian, v.ChannelMask())
 		//binary.Write(buf, binary.LittleEndian, uint16(0))            // reserved
-		guid := [16]byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71}
+		guid := subFormatGUID
+		binary.LittleEndian.PutUint16(guid[:2], v.SubFormat())
 		binary.Write(buf, binary.BigEndian, guid)
+	}
+	if writeFactChunk {
 		binary.Write(buf, binary.BigEndian, []byte("fact"))                           // fact chunk is an optional chunk
 		binary.Write(buf, binary.LittleEndian, uint32(4))                             // 4 bytes
 		binary.Write(buf, binary.LittleEndian, uint32(v.length/uint32(v.blockAlign))) // zero padding
 	}
 
+	for _, chunk := range leading {
+		chunk.writeTo(buf)
+	}
+	if junkSize > 0 {
+		buf.WriteString("JUNK")
+		binary.Write(buf, binary.LittleEndian, uint32(junk))
+		buf.Write(make([]byte, junkSize-8))
+	}
+
 	binary.Write(buf, binary.BigEndian, []byte("data"))
 	binary.Write(buf, binary.LittleEndian, v.length)
-	binary.Write(buf, binary.LittleEndian, v.data)
-	stream = buf.Bytes()
+	buf.Write(v.data)
+	if padding == 1 {
+		buf.WriteByte(0)
+	}
+	for _, chunk := range trailing {
+		chunk.writeTo(buf)
+	}
 
 	return
 }
@@ -492,6 +1288,7 @@ func New(samplesPerSec, bitsPerSample, channels int) (*File, error) {
 		return nil, fmt.Errorf("wav: invalid bits per sample (%v bit)", bitsPerSample)
 	}
 
+	audio.subFormat = WAVE_FORMAT_PCM
 	audio.samplesPerSec = uint32(samplesPerSec)
 	audio.channels = uint16(channels)
 	audio.bitsPerSample = uint16(bitsPerSample)
@@ -501,3 +1298,97 @@ func New(samplesPerSec, bitsPerSample, channels int) (*File, error) {
 
 	return audio, nil
 }
+
+// NewFromFormat creates an empty File which has the format f.
+// If f.FormatTag is 0, it is chosen by the bits per sample in the same way as New.
+func NewFromFormat(f Format) (*File, error) {
+	if !(f.FormatTag == 0 || f.FormatTag == WAVE_FORMAT_PCM || f.FormatTag == WAVE_FORMAT_EXTENSIBLE) {
+		return nil, fmt.Errorf("%w '%v'", ErrInvalidFormatTag, f.FormatTag)
+	}
+	if f.SampleRate <= 0 || f.Channels <= 0 || f.Channels > math.MaxUint16 {
+		return nil, fmt.Errorf("wav: invalid format (%v Hz, %v channel(s))", f.SampleRate, f.Channels)
+	}
+	if bits := f.BitsPerSample; !(bits == 8 || bits == 16 || bits == 24 || bits == 32) {
+		return nil, fmt.Errorf("wav: invalid bits per sample (%v bit)", bits)
+	}
+
+	audio, err := New(f.SampleRate, f.BitsPerSample, f.Channels)
+	if err != nil {
+		return nil, err
+	}
+	if f.FormatTag != 0 {
+		audio.formatTag = f.FormatTag
+	}
+
+	return audio, nil
+}
+
+// NewFromPlanarInt16 creates a 16 bit File from non-interleaved samples, one slice per channel.
+// All slices must have the same length.
+func NewFromPlanarInt16(planes [][]int16, samplesPerSec int) (*File, error) {
+	if len(planes) == 0 {
+		return nil, fmt.Errorf("wav: no channels")
+	}
+
+	frames := len(planes[0])
+	for c, plane := range planes {
+		if len(plane) != frames {
+			return nil, fmt.Errorf("wav: channel %v has %v samples but channel 0 has %v", c, len(plane), frames)
+		}
+	}
+
+	audio, err := New(samplesPerSec, 16, len(planes))
+	if err != nil {
+		return nil, err
+	}
+
+	channels := len(planes)
+	data := make([]byte, frames*channels*2)
+	for i := 0; i < frames; i++ {
+		for c, plane := range planes {
+			binary.LittleEndian.PutUint16(data[(i*channels+c)*2:], uint16(plane[i]))
+		}
+	}
+	audio.Write(data)
+
+	return audio, nil
+}
+
+// NewFromFloat64s creates a File from interleaved samples which are normalized to -1.0 to 1.0.
+// The samples are quantized to bitsPerSample and the out of range values are clamped.
+func NewFromFloat64s(samples []float64, samplesPerSec, bitsPerSample, channels int) (*File, error) {
+	if channels <= 0 || len(samples)%channels != 0 {
+		return nil, fmt.Errorf("wav: number of samples (%v) is not a multiple of channels (%v)", len(samples), channels)
+	}
+	if !(bitsPerSample == 8 || bitsPerSample == 16 || bitsPerSample == 24 || bitsPerSample == 32) {
+		return nil, fmt.Errorf("wav: invalid bits per sample (%v bit)", bitsPerSample)
+	}
+
+	audio, err := New(samplesPerSec, bitsPerSample, channels)
+	if err != nil {
+		return nil, err
+	}
+	audio.setFloat64s(samples)
+
+	return audio, nil
+}
+
+// NewFromInt16s creates a 16 bit File from interleaved samples.
+func NewFromInt16s(samples []int16, samplesPerSec, channels int) (*File, error) {
+	if channels <= 0 || len(samples)%channels != 0 {
+		return nil, fmt.Errorf("wav: number of samples (%v) is not a multiple of channels (%v)", len(samples), channels)
+	}
+
+	audio, err := New(samplesPerSec, 16, channels)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, len(samples)*2)
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(s))
+	}
+	audio.Write(data)
+
+	return audio, nil
+}
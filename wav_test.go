@@ -2,11 +2,16 @@ package wav
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
@@ -34,6 +39,78 @@ func TestNew(t *testing.T) {
 	return
 }
 
+func TestBitRate(t *testing.T) {
+	var a *File
+	var err error
+
+	if a, err = New(44100, 16, 2); err != nil {
+		t.Fatal(err)
+	}
+	if a.BitRate() != 1411200 {
+		t.Fatalf("expected: 1411200 actual: %v", a.BitRate())
+	}
+	return
+}
+
+func TestString(t *testing.T) {
+	var a *File
+	var err error
+
+	if a, err = New(44100, 16, 2); err != nil {
+		t.Fatal(err)
+	}
+	a.Write(make([]byte, 44100*4*7/2))
+
+	expected := "44100 Hz / 16 bit / 2ch / PCM / 3.50s"
+	if a.String() != expected {
+		t.Fatalf("expected: %v actual: %v", expected, a.String())
+	}
+
+	if a, err = New(96000, 24, 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"96000 Hz", "24 bit", "1ch", "extensible PCM", "0.00s"} {
+		if !strings.Contains(a.String(), field) {
+			t.Fatalf("%q must contain %q", a.String(), field)
+		}
+	}
+	return
+}
+
+func TestSetSampleRate(t *testing.T) {
+	var audio *File
+	var file []byte
+	var err error
+
+	audio = &File{}
+	if file, err = ioutil.ReadFile("./testdata/sawtooth.wav"); err != nil {
+		t.Fatal(err)
+	}
+	if err = Unmarshal(file, audio); err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := 250*time.Millisecond, audio.Duration(); expected != actual {
+		t.Fatalf("expected: %v actual: %v", expected, actual)
+	}
+
+	length := audio.Length()
+	audio.SetSampleRate(22050)
+
+	if audio.SamplesPerSec() != 22050 {
+		t.Fatalf("expected: 22050 actual: %v", audio.SamplesPerSec())
+	}
+	if audio.AvgBytesPerSec() != 44100 {
+		t.Fatalf("expected: 44100 actual: %v", audio.AvgBytesPerSec())
+	}
+	if expected, actual := 500*time.Millisecond, audio.Duration(); expected != actual {
+		t.Fatalf("expected: %v actual: %v", expected, actual)
+	}
+	if audio.Length() != length {
+		t.Fatalf("expected: %v actual: %v", length, audio.Length())
+	}
+	return
+}
+
 func TestUnmarshal(t *testing.T) {
 	var audio *File
 	var filename string
@@ -74,6 +151,592 @@ func TestUnmarshal(t *testing.T) {
 	return
 }
 
+func TestUnmarshalShared(t *testing.T) {
+	var audio *File
+	var file []byte
+	var err error
+
+	if file, err = ioutil.ReadFile("./testdata/sawtooth.wav"); err != nil {
+		t.Fatal(err)
+	}
+	expected := &File{}
+	if err = Unmarshal(file, expected); err != nil {
+		t.Fatal(err)
+	}
+	audio = &File{}
+	if err = UnmarshalShared(file, audio); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expected.Bytes(), audio.Bytes()) {
+		t.Fatalf("expected: %v actual: %v", expected.Bytes(), audio.Bytes())
+	}
+
+	file[44] = ^file[44]
+	if audio.Bytes()[0] != file[44] {
+		t.Fatalf("data must share the memory with stream")
+	}
+	return
+}
+
+func TestUnmarshalLimit(t *testing.T) {
+	var file []byte
+	var err error
+
+	filename := "./testdata/44100Hz-16bit-1ch-huge.wav"
+	if file, err = ioutil.ReadFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err = UnmarshalLimit(file, &File{}, 1<<20); err == nil {
+		t.Fatalf("error must not be nil (%v)", filename)
+	}
+
+	filename = "./testdata/sawtooth.wav"
+	if file, err = ioutil.ReadFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err = UnmarshalLimit(file, &File{}, 22050); err != nil {
+		t.Fatal(err)
+	}
+	if err = UnmarshalLimit(file, &File{}, 22049); err == nil {
+		t.Fatalf("error must not be nil (%v)", filename)
+	}
+	return
+}
+
+func TestUnmarshal_LeadingChunk(t *testing.T) {
+	filenames := []string{
+		"./testdata/44100Hz-16bit-1ch-junk.wav",
+		"./testdata/44100Hz-16bit-1ch-list.wav",
+	}
+	expected := []int16{0, 1000, -1000, 2000}
+
+	for _, filename := range filenames {
+		file, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoders := map[string]func() (*File, error){
+			"Unmarshal": func() (*File, error) {
+				audio := &File{}
+				return audio, Unmarshal(file, audio)
+			},
+			"DecodeReader": func() (*File, error) {
+				return DecodeReader(bytes.NewReader(file))
+			},
+		}
+		for name, decode := range decoders {
+			audio, err := decode()
+			if err != nil {
+				t.Fatalf("%v (%v %v)", err, name, filename)
+			}
+			if audio.SamplesPerSec() != 44100 || audio.BitsPerSample() != 16 || audio.Channels() != 1 {
+				t.Fatalf("expected: 44100 Hz 16 bit 1 ch actual: %v (%v %v)", audio, name, filename)
+			}
+			if actual := audio.Int16s(); fmt.Sprint(expected) != fmt.Sprint(actual) {
+				t.Fatalf("expected: %v actual: %v (%v %v)", expected, actual, name, filename)
+			}
+		}
+	}
+	return
+}
+
+func TestUnmarshal_TrailingChunk(t *testing.T) {
+	var file []byte
+	var err error
+
+	filename := "./testdata/8000Hz-8bit-1ch-trailing.wav"
+	if file, err = ioutil.ReadFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{128, 160, 192, 160, 128}
+	decoders := map[string]func() (*File, error){
+		"Unmarshal": func() (*File, error) {
+			audio := &File{}
+			return audio, Unmarshal(file, audio)
+		},
+		"UnmarshalShared": func() (*File, error) {
+			audio := &File{}
+			return audio, UnmarshalShared(file, audio)
+		},
+		"DecodeReader": func() (*File, error) {
+			return DecodeReader(bytes.NewReader(file))
+		},
+	}
+	for name, decode := range decoders {
+		audio, err := decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(expected, audio.Bytes()) || audio.Length() != len(expected) {
+			t.Fatalf("expected: %v actual: %v (%v)", expected, audio.Bytes(), name)
+		}
+	}
+
+	// id3 chunk follows the data chunk, and bext chunk precedes it.
+	if file, err = ioutil.ReadFile("./testdata/44100Hz-16bit-1ch-id3.wav"); err != nil {
+		t.Fatal(err)
+	}
+	for name, decode := range decoders {
+		audio, err := decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tags := audio.ID3(); tags == nil || tags.Title != "Sawtooth" {
+			t.Fatalf("expected: %v actual: %v (%v)", "Sawtooth", tags, name)
+		}
+	}
+	if file, err = ioutil.ReadFile("./testdata/44100Hz-16bit-1ch-bext.wav"); err != nil {
+		t.Fatal(err)
+	}
+	for name, decode := range decoders {
+		audio, err := decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reference, ok := audio.TimeReference(); !ok || reference != 158760000 {
+			t.Fatalf("expected: %v actual: %v (%v)", 158760000, reference, name)
+		}
+	}
+	return
+}
+
+func TestUnmarshal_FmtChunkSize(t *testing.T) {
+	var audio *File
+	var file []byte
+	var err error
+
+	filename := "./testdata/44100Hz-16bit-1ch-fmt18.wav"
+	if file, err = ioutil.ReadFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	audio = &File{}
+	if err = Unmarshal(file, audio); err != nil {
+		t.Fatal(err)
+	}
+	if audio.Length() != 16 {
+		t.Fatalf("expected: 16 actual: %v (%v)", audio.Length(), filename)
+	}
+
+	expected := []int32{0, 1000, 2000, 3000, -1000, -2000, -3000, 32767}
+	for i, s := range audio.Int32s() {
+		if s != expected[i]<<16 {
+			t.Fatalf("[%v] expected: %v actual: %v (%v)", i, expected[i]<<16, s, filename)
+		}
+	}
+	return
+}
+
+func TestUnmarshal_Float(t *testing.T) {
+	var actualBytes, file []byte
+	var audio *File
+	var err error
+
+	filename := "./testdata/44100Hz-32bit-2ch-float.wav"
+	if file, err = ioutil.ReadFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	audio = &File{}
+	if err = Unmarshal(file, audio); err != nil {
+		t.Fatal(err)
+	}
+	if audio.SubFormat() != WAVE_FORMAT_IEEE_FLOAT {
+		t.Fatalf("expected: %v actual: %v (%v)", WAVE_FORMAT_IEEE_FLOAT, audio.SubFormat(), filename)
+	}
+
+	expected := []float64{0.0, 0.5, -0.5, 0.25, 1.0, -1.0, 0.125, -0.75}
+	actual := audio.Float64s()
+	if len(expected) != len(actual) {
+		t.Fatalf("expected: %v actual: %v (%v)", len(expected), len(actual), filename)
+	}
+	for i := range expected {
+		if expected[i] != actual[i] {
+			t.Fatalf("[%v] expected: %v actual: %v (%v)", i, expected[i], actual[i], filename)
+		}
+	}
+	if s32 := audio.Int32s(); s32[1] != 1<<30 {
+		t.Fatalf("expected: %v actual: %v (%v)", 1<<30, s32[1], filename)
+	}
+	if actualBytes, err = Marshal(audio); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(file, actualBytes) {
+		t.Fatalf("expected: %v actual: %v (%v)", file, actualBytes, filename)
+	}
+	return
+}
+
+func TestChannelMask(t *testing.T) {
+	var actualBytes, file []byte
+	var audio *File
+	var err error
+
+	filename := "./testdata/48000Hz-24bit-6ch-5.1.wav"
+	if file, err = ioutil.ReadFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	audio = &File{}
+	if err = Unmarshal(file, audio); err != nil {
+		t.Fatal(err)
+	}
+	if audio.ChannelMask() != 0x60F {
+		t.Fatalf("expected: 0x60F actual: %#x (%v)", audio.ChannelMask(), filename)
+	}
+
+	expected := []string{"FL", "FR", "FC", "LFE", "SL", "SR"}
+	actual := audio.SpeakerLayout()
+	if fmt.Sprint(expected) != fmt.Sprint(actual) {
+		t.Fatalf("expected: %v actual: %v (%v)", expected, actual, filename)
+	}
+	if actualBytes, err = Marshal(audio); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(file, actualBytes) {
+		t.Fatalf("channel mask must be preserved (%v)", filename)
+	}
+
+	stereo, _ := New(48000, 24, 2)
+	if stereo.ChannelMask() != 0x3 {
+		t.Fatalf("expected: 0x3 actual: %#x", stereo.ChannelMask())
+	}
+	return
+}
+
+func TestChannelOrder(t *testing.T) {
+	filename := "./testdata/48000Hz-24bit-6ch-5.1.wav"
+	file, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"FL", "FR", "FC", "LFE", "SL", "SR"}
+	if actual := audio.ChannelOrder(); fmt.Sprint(expected) != fmt.Sprint(actual) {
+		t.Fatalf("expected: %v actual: %v (%v)", expected, actual, filename)
+	}
+
+	stereo, _ := New(44100, 16, 2)
+	expected = []string{"FL", "FR"}
+	if actual := stereo.ChannelOrder(); fmt.Sprint(expected) != fmt.Sprint(actual) {
+		t.Fatalf("expected: %v actual: %v", expected, actual)
+	}
+
+	// The third channel is not assigned to any speaker.
+	audio, _ = New(44100, 24, 3)
+	audio.SetChannelMask(0x3)
+	expected = []string{"FL", "FR", ""}
+	if actual := audio.ChannelOrder(); fmt.Sprint(expected) != fmt.Sprint(actual) {
+		t.Fatalf("expected: %q actual: %q", expected, actual)
+	}
+	return
+}
+
+func TestDeclaredVsActual(t *testing.T) {
+	filename := "./testdata/44100Hz-16bit-1ch-truncated.wav"
+	file, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if declared, actual := audio.DeclaredVsActual(); declared != 100 || actual != 20 {
+		t.Fatalf("expected: 100 20 actual: %v %v (%v)", declared, actual, filename)
+	}
+	if audio.Length() != 20 || audio.Frames() != 10 {
+		t.Fatalf("expected: 20 actual: %v (%v)", audio.Length(), filename)
+	}
+
+	filename = "./testdata/sawtooth.wav"
+	if file, err = ioutil.ReadFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	if audio, err = Decode(file); err != nil {
+		t.Fatal(err)
+	}
+	if declared, actual := audio.DeclaredVsActual(); declared != actual {
+		t.Fatalf("expected: %v actual: %v (%v)", declared, actual, filename)
+	}
+	return
+}
+
+func TestDeclaredVsActual_Edit(t *testing.T) {
+	// The truncated file declares 50 frames, but only 10 frames are actually parsed.
+	file, err := ioutil.ReadFile("./testdata/44100Hz-16bit-1ch-truncated.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := time.Second / 44100
+	other, _ := NewFromInt16s([]int16{1, 2}, 44100, 1)
+
+	edits := map[string]func(audio *File){
+		"Float64s":          func(audio *File) { audio.Float64s() },
+		"Float32s":          func(audio *File) { audio.Float32s() },
+		"FloatFrames":       func(audio *File) { audio.FloatFrames() },
+		"Int16s":            func(audio *File) { audio.Int16s() },
+		"S24":               func(audio *File) { audio.S24() },
+		"PlanarInt16":       func(audio *File) { audio.PlanarInt16() },
+		"Map":               func(audio *File) { audio.Map(func(f float64) float64 { return -f }) },
+		"Pan":               func(audio *File) { audio.Pan(0.5) },
+		"Saturate":          func(audio *File) { audio.Saturate(2) },
+		"BitCrush":          func(audio *File) { audio.BitCrush(4) },
+		"Declip":            func(audio *File) { audio.Declip() },
+		"SwapSampleBytes":   func(audio *File) { audio.SwapSampleBytes() },
+		"Gate":              func(audio *File) { audio.Gate(0.1, frame, frame) },
+		"Compress":          func(audio *File) { audio.Compress(-20, 4, frame, frame) },
+		"Limit":             func(audio *File) { audio.Limit(-1, 2*frame) },
+		"ApplyEnvelope":     func(audio *File) { audio.ApplyEnvelope(frame, frame, frame, frame, 0.5) },
+		"AutomateGain":      func(audio *File) { audio.AutomateGain([]GainPoint{{At: 0, Gain: 1}, {At: 40 * frame, Gain: 0}}) },
+		"Mute":              func(audio *File) { audio.Mute(0, 40*frame) },
+		"Bleep":             func(audio *File) { audio.Bleep(0, 40*frame, 1000) },
+		"Delete":            func(audio *File) { audio.Delete(0, 40*frame) },
+		"Insert":            func(audio *File) { audio.Insert(40*frame, other) },
+		"Prepend":           func(audio *File) { audio.Prepend(other) },
+		"Float64sRange":     func(audio *File) { audio.Float64sRange(0, 40*frame) },
+		"Split":             func(audio *File) { audio.Split(4 * frame) },
+		"SplitChannels":     func(audio *File) { audio.SplitChannels() },
+		"SplitOnSilence":    func(audio *File) { audio.SplitOnSilence(0.1, frame) },
+		"LoudestRegion":     func(audio *File) { audio.LoudestRegion(40 * frame) },
+		"ConvertBitDepth":   func(audio *File) { audio.ConvertBitDepth(24) },
+		"ExpandChannels":    func(audio *File) { audio.ExpandChannels(2, []int{1}) },
+		"Resample":          func(audio *File) { audio.Resample(22050) },
+		"ResampleSinc":      func(audio *File) { audio.ResampleSinc(22050, 16) },
+		"ChangeSpeed":       func(audio *File) { audio.ChangeSpeed(2) },
+		"Raw":               func(audio *File) { audio.Raw(RawFormat{BitsPerSample: 24, Planar: true}) },
+		"StreamS16":         func(audio *File) { audio.StreamS16(ioutil.Discard) },
+		"WaveformPeaks":     func(audio *File) { audio.WaveformPeaks(8) },
+		"ClippingRuns":      func(audio *File) { audio.ClippingRuns() },
+		"TruePeak":          func(audio *File) { audio.TruePeak() },
+		"MarshalAIFF":       func(audio *File) { audio.MarshalAIFF() },
+		"MarshalCAF":        func(audio *File) { audio.MarshalCAF() },
+		"DataURL":           func(audio *File) { audio.DataURL() },
+		"NormalizeLoudness": func(audio *File) { audio.NormalizeLoudness(-23) },
+	}
+	for name, edit := range edits {
+		audio, err := Decode(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%v must not panic: %v", name, r)
+				}
+			}()
+			edit(audio)
+		}()
+	}
+	return
+}
+
+func TestRepairHeader(t *testing.T) {
+	filename := "./testdata/44100Hz-16bit-2ch-badheader.wav"
+	file, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audio.BlockAlign() != 2 || audio.AvgBytesPerSec() != 12345 {
+		t.Fatalf("expected: 2 12345 actual: %v %v (%v)", audio.BlockAlign(), audio.AvgBytesPerSec(), filename)
+	}
+
+	audio.RepairHeader()
+
+	if audio.BlockAlign() != 4 || audio.AvgBytesPerSec() != 176400 || audio.Frames() != 4 {
+		t.Fatalf("expected: 4 176400 4 actual: %v %v %v (%v)", audio.BlockAlign(), audio.AvgBytesPerSec(), audio.Frames(), filename)
+	}
+
+	stream, err := Marshal(audio)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if binary.LittleEndian.Uint32(stream[28:32]) != 176400 || binary.LittleEndian.Uint16(stream[32:34]) != 4 {
+		t.Fatalf("repaired fields must be written (%v)", filename)
+	}
+	return
+}
+
+func TestFormat(t *testing.T) {
+	formats := []Format{
+		{SampleRate: 44100, Channels: 2, BitsPerSample: 16, FormatTag: WAVE_FORMAT_PCM},
+		{SampleRate: 96000, Channels: 6, BitsPerSample: 24, FormatTag: WAVE_FORMAT_EXTENSIBLE},
+		{SampleRate: 8000, Channels: 1, BitsPerSample: 8, FormatTag: WAVE_FORMAT_PCM},
+	}
+	for _, expected := range formats {
+		audio, err := New(expected.SampleRate, expected.BitsPerSample, expected.Channels)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := audio.Format(); expected != actual {
+			t.Fatalf("expected: %+v actual: %+v", expected, actual)
+		}
+
+		if audio, err = NewFromFormat(expected); err != nil {
+			t.Fatal(err)
+		}
+		if actual := audio.Format(); expected != actual {
+			t.Fatalf("expected: %+v actual: %+v", expected, actual)
+		}
+	}
+
+	audio, err := NewFromFormat(Format{SampleRate: 48000, Channels: 2, BitsPerSample: 16, FormatTag: WAVE_FORMAT_EXTENSIBLE})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audio.FormatTag() != WAVE_FORMAT_EXTENSIBLE || audio.BlockAlign() != 4 {
+		t.Fatalf("expected: %v 4 actual: %v %v", WAVE_FORMAT_EXTENSIBLE, audio.FormatTag(), audio.BlockAlign())
+	}
+
+	if _, err = NewFromFormat(Format{SampleRate: 44100, Channels: 2, BitsPerSample: 16, FormatTag: WAVE_FORMAT_IEEE_FLOAT}); !errors.Is(err, ErrInvalidFormatTag) {
+		t.Fatalf("expected: %v actual: %v", ErrInvalidFormatTag, err)
+	}
+	if _, err = NewFromFormat(Format{SampleRate: 44100, Channels: 0, BitsPerSample: 16}); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if _, err = NewFromFormat(Format{SampleRate: 44100, Channels: 2}); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
+
+func TestDecode(t *testing.T) {
+	var audio *File
+	var file []byte
+	var err error
+
+	if file, err = ioutil.ReadFile("./testdata/sawtooth.wav"); err != nil {
+		t.Fatal(err)
+	}
+	if audio, err = Decode(file); err != nil {
+		t.Fatal(err)
+	}
+	if audio.SamplesPerSec() != 44100 || audio.BitsPerSample() != 16 || audio.Channels() != 1 {
+		t.Fatalf("expected: 44100 Hz / 16 bit / 1ch actual: %v", audio)
+	}
+	if audio.Length() != 22050 {
+		t.Fatalf("expected: 22050 actual: %v", audio.Length())
+	}
+	if audio, err = Decode(file[:8]); err == nil || audio != nil {
+		t.Fatalf("expected: nil and error actual: %v, %v", audio, err)
+	}
+	return
+}
+
+func TestUnmarshal_Errors(t *testing.T) {
+	var file []byte
+	var err error
+
+	if file, err = ioutil.ReadFile("./testdata/sawtooth.wav"); err != nil {
+		t.Fatal(err)
+	}
+
+	modify := func(offset int, b []byte) []byte {
+		stream := append([]byte{}, file...)
+		copy(stream[offset:], b)
+		return stream
+	}
+
+	tt := []struct {
+		stream   []byte
+		expected error
+	}{
+		{[]byte("RIFF"), ErrNotRIFF},
+		{modify(0, []byte("RIFX")), ErrNotRIFF},
+		{modify(8, []byte("AVI ")), ErrNotRIFF},
+		{file[:30], ErrTruncatedData},
+		{modify(20, []byte{0x55, 0x00}), ErrInvalidFormatTag},
+		{modify(16, []byte{0x11}), ErrInvalidFmtChunk},
+		{modify(36, []byte("junk")), ErrDataChunkNotFound},
+	}
+	for i, v := range tt {
+		err = Unmarshal(v.stream, &File{})
+		if !errors.Is(err, v.expected) {
+			t.Errorf("[%v] expected: %v actual: %v", i, v.expected, err)
+		}
+	}
+
+	if err = UnmarshalLimit(file, &File{}, 1); !errors.Is(err, ErrDataTooLarge) {
+		t.Errorf("expected: %v actual: %v", ErrDataTooLarge, err)
+	}
+	if _, err = Marshal(&File{}); !errors.Is(err, ErrInvalidFormatTag) {
+		t.Errorf("expected: %v actual: %v", ErrInvalidFormatTag, err)
+	}
+	return
+}
+
+func TestFormatExtension(t *testing.T) {
+	var actualBytes, file []byte
+	var audio *File
+	var err error
+
+	filename := "./testdata/48000Hz-24bit-2ch-20valid.wav"
+	if file, err = ioutil.ReadFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	if audio, err = Decode(file); err != nil {
+		t.Fatal(err)
+	}
+
+	extension := audio.FormatExtension()
+	if !bytes.Equal(extension, file[38:60]) {
+		t.Fatalf("expected: %v actual: %v (%v)", file[38:60], extension, filename)
+	}
+	if validBits := binary.LittleEndian.Uint16(extension); validBits != 20 {
+		t.Fatalf("expected: 20 actual: %v (%v)", validBits, filename)
+	}
+	if actualBytes, err = Marshal(audio); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(file, actualBytes) {
+		t.Fatalf("extension must be preserved (%v)", filename)
+	}
+
+	audio.SetChannelMask(0x600)
+	if actualBytes, err = Marshal(audio); err != nil {
+		t.Fatal(err)
+	}
+	if audio, err = Decode(actualBytes); err != nil {
+		t.Fatal(err)
+	}
+	if audio.ChannelMask() != 0x600 {
+		t.Fatalf("expected: 0x600 actual: %#x (%v)", audio.ChannelMask(), filename)
+	}
+
+	// The extension whose channel mask has the reserved bit is written back as is.
+	stream := append([]byte{}, file...)
+	binary.LittleEndian.PutUint32(stream[40:44], 0x80000003)
+	if audio, err = Decode(stream); err != nil {
+		t.Fatal(err)
+	}
+	if actualBytes, err = Marshal(audio); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stream, actualBytes) {
+		t.Fatalf("extension must be preserved (%v)", filename)
+	}
+
+	// The vendor-specific subformat is out of scope.
+	stream = append([]byte{}, file...)
+	copy(stream[44:60], []byte("vendor-subformat"))
+	if _, err = Decode(stream); !errors.Is(err, ErrInvalidFormatTag) {
+		t.Fatalf("expected: %v actual: %v", ErrInvalidFormatTag, err)
+	}
+
+	pcm, _ := New(44100, 16, 2)
+	if len(pcm.FormatExtension()) != 0 {
+		t.Fatalf("expected: empty actual: %v", pcm.FormatExtension())
+	}
+	return
+}
+
 func TestMarshal(t *testing.T) {
 	var actualBytes, expectedBytes, file []byte
 	var audio *File
@@ -81,34 +744,323 @@ func TestMarshal(t *testing.T) {
 
 	filenames := []string{
 		"./testdata/sawtooth.wav",
+		"./testdata/8000Hz-8bit-1ch-odd.wav",
+	}
+	for _, filename := range filenames {
+		if file, err = ioutil.ReadFile(filename); err != nil {
+			t.Fatal(err)
+		}
+		audio = &File{}
+		if err = Unmarshal(file, audio); err != nil {
+			t.Fatal(err)
+		}
+		if expectedBytes, err = ioutil.ReadFile(filename); err != nil {
+			t.Fatal(err)
+		}
+		if actualBytes, err = Marshal(audio); err != nil {
+			t.Fatal(err)
+		}
+
+		sizeOfExpectedBytes := len(expectedBytes)
+		sizeOfActualBytes := len(actualBytes)
+
+		if sizeOfExpectedBytes != sizeOfActualBytes {
+			t.Fatalf("expected: %d actual: %d (%v)", sizeOfExpectedBytes, sizeOfActualBytes, filename)
+		}
+		for i, b := range expectedBytes {
+			if b != actualBytes[i] {
+				t.Fatalf("[%v] expected: %v actual: %v\n(%v)", i, b, actualBytes[i], filename)
+			}
+		}
+	}
+	return
+}
+
+func TestMarshalTo(t *testing.T) {
+	var expectedBytes, file []byte
+	var err error
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("garbage")
+
+	for _, filename := range []string{"./testdata/sawtooth.wav", "./testdata/96000Hz-24bit-2ch-empty.wav"} {
+		if file, err = ioutil.ReadFile(filename); err != nil {
+			t.Fatal(err)
+		}
+		audio := &File{}
+		if err = Unmarshal(file, audio); err != nil {
+			t.Fatal(err)
+		}
+		if expectedBytes, err = Marshal(audio); err != nil {
+			t.Fatal(err)
+		}
+		if err = MarshalTo(audio, buf); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(expectedBytes, buf.Bytes()) {
+			t.Fatalf("expected: %v actual: %v (%v)", len(expectedBytes), buf.Len(), filename)
+		}
+	}
+
+	if err = MarshalTo(&File{}, buf); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected: 0 actual: %v", buf.Len())
+	}
+	return
+}
+
+func TestMarshal_OddLength(t *testing.T) {
+	var actualBytes, file []byte
+	var audio *File
+	var err error
+
+	filename := "./testdata/8000Hz-8bit-1ch-odd.wav"
+	if file, err = ioutil.ReadFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	audio = &File{}
+	if err = Unmarshal(file, audio); err != nil {
+		t.Fatal(err)
+	}
+	if audio.Length() != 7 {
+		t.Fatalf("expected: 7 actual: %v (%v)", audio.Length(), filename)
+	}
+	if actualBytes, err = Marshal(audio); err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := 44+audio.Length()+1, len(actualBytes); expected != actual {
+		t.Fatalf("expected: %d actual: %d (%v)", expected, actual, filename)
+	}
+	if actualBytes[len(actualBytes)-1] != 0 {
+		t.Fatalf("expected: 0 actual: %v (%v)", actualBytes[len(actualBytes)-1], filename)
+	}
+	if expected, actual := uint32(len(actualBytes)-8), binary.LittleEndian.Uint32(actualBytes[4:8]); expected != actual {
+		t.Fatalf("expected: %d actual: %d (%v)", expected, actual, filename)
+	}
+	return
+}
+
+func TestMarshalSize(t *testing.T) {
+	filenames := []string{
+		"./testdata/sawtooth.wav",
+		"./testdata/8000Hz-8bit-1ch-odd.wav",
+		"./testdata/44100Hz-16bit-1ch-fmt18.wav",
+		"./testdata/96000Hz-24bit-2ch-empty.wav",
+		"./testdata/44100Hz-32bit-2ch-float.wav",
+		"./testdata/48000Hz-24bit-6ch-5.1.wav",
+	}
+	for _, filename := range filenames {
+		file, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		audio, err := Decode(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream, err := Marshal(audio)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if audio.MarshalSize() != len(stream) {
+			t.Fatalf("expected: %v actual: %v (%v)", len(stream), audio.MarshalSize(), filename)
+		}
+	}
+	if size := (&File{}).MarshalSize(); size != 0 {
+		t.Fatalf("expected: 0 actual: %v", size)
+	}
+	return
+}
+
+func TestMarshal_TooLarge(t *testing.T) {
+	audio, _ := New(44100, 16, 2)
+	audio.Write([]byte{0, 0, 0, 0})
+
+	// Pretend that the audio is near 4 GB without allocating it.
+	audio.length = math.MaxUint32 - 32
+
+	if _, err := Marshal(audio); !errors.Is(err, ErrDataTooLarge) {
+		t.Fatalf("expected: %v actual: %v", ErrDataTooLarge, err)
+	}
+	if size := audio.MarshalSize(); size != 0 {
+		t.Fatalf("expected: 0 actual: %v", size)
+	}
+
+	audio.length = math.MaxUint32 - 37
+	if size := audio.MarshalSize(); size != math.MaxUint32+7 {
+		t.Fatalf("expected: %v actual: %v", uint64(math.MaxUint32+7), size)
+	}
+	return
+}
+
+func TestDataURL(t *testing.T) {
+	filename := "./testdata/8000Hz-8bit-1ch-odd.wav"
+	file, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url, err := audio.DataURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const prefix = "data:audio/wav;base64,"
+	if !strings.HasPrefix(url, prefix) {
+		t.Fatalf("expected: %v actual: %v (%v)", prefix, url, filename)
+	}
+
+	stream, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(url, prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Format() != audio.Format() || !bytes.Equal(decoded.Bytes(), audio.Bytes()) {
+		t.Fatalf("expected: %v actual: %v (%v)", audio, decoded, filename)
+	}
+
+	if _, err = (&File{}).DataURL(); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
+
+func TestMarshalWith(t *testing.T) {
+	var actualBytes, file []byte
+	var audio *File
+	var err error
+
+	filename := "./testdata/96000Hz-24bit-2ch-empty.wav"
+	if file, err = ioutil.ReadFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	audio = &File{}
+	if err = Unmarshal(file, audio); err != nil {
+		t.Fatal(err)
+	}
+	if actualBytes, err = MarshalWith(audio, MarshalOptions{WriteFactChunk: false}); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(actualBytes, []byte("fact")) {
+		t.Fatalf("fact chunk must not be written (%v)", filename)
+	}
+	if expected, actual := len(file)-12, len(actualBytes); expected != actual {
+		t.Fatalf("expected: %d actual: %d (%v)", expected, actual, filename)
+	}
+	if expected, actual := uint32(len(actualBytes)-8), binary.LittleEndian.Uint32(actualBytes[4:8]); expected != actual {
+		t.Fatalf("expected: %d actual: %d (%v)", expected, actual, filename)
+	}
+	if actualBytes, err = MarshalWith(audio, MarshalOptions{WriteFactChunk: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(file, actualBytes) {
+		t.Fatalf("expected: %v actual: %v (%v)", file, actualBytes, filename)
+	}
+	return
+}
+
+func TestMarshalWith_JunkChunk(t *testing.T) {
+	filename := "./testdata/sawtooth.wav"
+	file, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := MarshalWith(audio, MarshalOptions{JunkSize: 27})
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := bytes.Index(stream, []byte("JUNK"))
+	if offset < 0 || offset > bytes.Index(stream, []byte("data")) {
+		t.Fatalf("JUNK chunk must be written before data chunk (%v)", filename)
+	}
+	if size := binary.LittleEndian.Uint32(stream[offset+4 : offset+8]); size != 27 {
+		t.Fatalf("expected: 27 actual: %v (%v)", size, filename)
+	}
+	// 27 bytes of JUNK chunk is followed by a pad byte.
+	if expected, actual := len(file)+8+28, len(stream); expected != actual {
+		t.Fatalf("expected: %v actual: %v (%v)", expected, actual, filename)
+	}
+	if expected, actual := uint32(len(stream)-8), binary.LittleEndian.Uint32(stream[4:8]); expected != actual {
+		t.Fatalf("expected: %v actual: %v (%v)", expected, actual, filename)
+	}
+
+	decoded, err := Decode(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(audio.Bytes(), decoded.Bytes()) {
+		t.Fatalf("audio samples must be preserved (%v)", filename)
+	}
+
+	if _, err = MarshalWith(audio, MarshalOptions{JunkSize: -1}); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
+
+func TestMarshalWith_DataAlignment(t *testing.T) {
+	filenames := []string{
+		"./testdata/sawtooth.wav",
+		"./testdata/44100Hz-32bit-2ch-float.wav",
+		"./testdata/8000Hz-8bit-1ch-odd.wav",
 	}
 	for _, filename := range filenames {
-		if file, err = ioutil.ReadFile(filename); err != nil {
-			t.Fatal(err)
-		}
-		audio = &File{}
-		if err = Unmarshal(file, audio); err != nil {
+		file, err := ioutil.ReadFile(filename)
+		if err != nil {
 			t.Fatal(err)
 		}
-		if expectedBytes, err = ioutil.ReadFile(filename); err != nil {
-			t.Fatal(err)
-		}
-		if actualBytes, err = Marshal(audio); err != nil {
+		audio, err := Decode(file)
+		if err != nil {
 			t.Fatal(err)
 		}
 
-		sizeOfExpectedBytes := len(expectedBytes)
-		sizeOfActualBytes := len(actualBytes)
+		for _, opts := range []MarshalOptions{
+			{DataAlignment: 4096},
+			{DataAlignment: 16},
+			{DataAlignment: 4096, JunkSize: 5000, WriteFactChunk: true},
+		} {
+			stream, err := MarshalWith(audio, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			offset := bytes.Index(stream, []byte("data")) + 8
+			if offset%opts.DataAlignment != 0 {
+				t.Fatalf("expected: multiple of %v actual: %v (%v)", opts.DataAlignment, offset, filename)
+			}
+			if junk := bytes.Index(stream, []byte("JUNK")); junk >= 0 && int(binary.LittleEndian.Uint32(stream[junk+4:])) < opts.JunkSize {
+				t.Fatalf("JUNK chunk must not be smaller than %v bytes (%v)", opts.JunkSize, filename)
+			}
+			if expected, actual := uint32(len(stream)-8), binary.LittleEndian.Uint32(stream[4:8]); expected != actual {
+				t.Fatalf("expected: %v actual: %v (%v)", expected, actual, filename)
+			}
 
-		if sizeOfExpectedBytes != sizeOfActualBytes {
-			t.Fatalf("expected: %d actual: %d (%v)", sizeOfExpectedBytes, sizeOfActualBytes, filename)
-		}
-		for i, b := range expectedBytes {
-			if b != actualBytes[i] {
-				t.Fatalf("[%v] expected: %v actual: %v\n(%v)", i, b, actualBytes[i], filename)
+			decoded, err := Decode(stream)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(audio.Bytes(), decoded.Bytes()) {
+				t.Fatalf("audio samples must be preserved (%v)", filename)
 			}
 		}
 	}
+
+	audio, _ := New(44100, 16, 2)
+	if _, err := MarshalWith(audio, MarshalOptions{DataAlignment: 100}); err == nil {
+		t.Fatalf("error must not be nil")
+	}
 	return
 }
 
@@ -144,6 +1096,33 @@ func TestRead_(t *testing.T) {
 	return
 }
 
+func TestRead_Partial(t *testing.T) {
+	audio, _ := New(8000, 8, 1)
+	audio.Write([]byte{1, 2, 3, 4, 5, 6, 7})
+
+	p := make([]byte, 3)
+	expected := []struct {
+		n    int
+		err  error
+		data []byte
+	}{
+		{3, nil, []byte{1, 2, 3}},
+		{3, nil, []byte{4, 5, 6}},
+		{1, nil, []byte{7}},
+		{0, io.EOF, []byte{}},
+	}
+	for i, e := range expected {
+		n, err := audio.Read(p)
+		if n != e.n || err != e.err {
+			t.Fatalf("[%v] expected: (%v, %v) actual: (%v, %v)", i, e.n, e.err, n, err)
+		}
+		if !bytes.Equal(p[:n], e.data) {
+			t.Fatalf("[%v] expected: %v actual: %v", i, e.data, p[:n])
+		}
+	}
+	return
+}
+
 func TestWrite_(t *testing.T) {
 	var n int64
 	var err error
@@ -162,6 +1141,29 @@ func TestWrite_(t *testing.T) {
 	return
 }
 
+func TestWrite_Bytes(t *testing.T) {
+	audio, _ := New(8000, 8, 1)
+
+	for _, b := range [][]byte{{1, 2, 3}, {}, {4, 5}} {
+		n, err := audio.Write(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != len(b) {
+			t.Fatalf("expected: %v actual: %v", len(b), n)
+		}
+	}
+
+	expected := []byte{1, 2, 3, 4, 5}
+	if !bytes.Equal(expected, audio.Bytes()) {
+		t.Fatalf("expected: %v actual: %v", expected, audio.Bytes())
+	}
+	if audio.Length() != len(expected) {
+		t.Fatalf("expected: %v actual: %v", len(expected), audio.Length())
+	}
+	return
+}
+
 func TestBytes(t *testing.T) {
 	var audio *File
 	var actualBytes, expectedBytes, file []byte
@@ -193,6 +1195,59 @@ func TestBytes(t *testing.T) {
 	return
 }
 
+func TestBytesCopy(t *testing.T) {
+	var audio *File
+	var file []byte
+	var err error
+
+	audio = &File{}
+	if file, err = ioutil.ReadFile("./testdata/sawtooth.wav"); err != nil {
+		t.Fatal(err)
+	}
+	if err = Unmarshal(file, audio); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedBytes := append([]byte{}, audio.Bytes()...)
+	actualBytes := audio.BytesCopy()
+
+	if !bytes.Equal(expectedBytes, actualBytes) {
+		t.Fatalf("expected: %v actual: %v", expectedBytes, actualBytes)
+	}
+	for i := range actualBytes {
+		actualBytes[i] = ^actualBytes[i]
+	}
+	if !bytes.Equal(expectedBytes, audio.Bytes()) {
+		t.Fatalf("modifying the copy must not change the audio")
+	}
+	return
+}
+
+func TestS8(t *testing.T) {
+	var audio *File
+	var file []byte
+	var err error
+
+	if file, err = ioutil.ReadFile("./testdata/8000Hz-8bit-1ch-odd.wav"); err != nil {
+		t.Fatal(err)
+	}
+	if audio, err = Decode(file); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []int8{0, 32, 64, 32, 0, -32, -64}
+	actual := audio.S8()
+	if len(expected) != len(actual) {
+		t.Fatalf("expected: %d actual: %d", len(expected), len(actual))
+	}
+	for i := range expected {
+		if int8(actual[i]) != expected[i] {
+			t.Fatalf("[%v] expected: %v actual: %v", i, expected[i], int8(actual[i]))
+		}
+	}
+	return
+}
+
 func TestInt32s(t *testing.T) {
 	var audio *File
 	var actualBytes, expectedBytes, file []byte
@@ -227,6 +1282,37 @@ func TestInt32s(t *testing.T) {
 	return
 }
 
+func TestInt32s_Direct(t *testing.T) {
+	for _, bits := range []int{8, 16, 24, 32} {
+		audio, err := NewWave(Sawtooth, 441, 100*time.Millisecond, 44100, bits, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The reference result of the conversion through 32 bit byte slice.
+		var s32 []byte
+		switch bits {
+		case 8:
+			s32 = audio.fromU8ToS32()
+		default:
+			s32 = audio.S32()
+		}
+		expected := make([]int32, audio.Samples())
+		binary.Read(bytes.NewBuffer(s32), binary.LittleEndian, &expected)
+
+		actual := audio.Int32s()
+		if len(expected) != len(actual) {
+			t.Fatalf("expected: %d actual: %d (%v bit)", len(expected), len(actual), bits)
+		}
+		for i := range expected {
+			if expected[i] != actual[i] {
+				t.Fatalf("[%v] expected: %v actual: %v (%v bit)", i, expected[i], actual[i], bits)
+			}
+		}
+	}
+	return
+}
+
 func TestFloat64s(t *testing.T) {
 	var audio *File
 	var actualBytes, expectedBytes, file []byte
@@ -260,3 +1346,326 @@ func TestFloat64s(t *testing.T) {
 	}
 	return
 }
+
+func TestCloseTo(t *testing.T) {
+	var a, b *File
+	var file []byte
+	var err error
+
+	if file, err = ioutil.ReadFile("./testdata/sawtooth.wav"); err != nil {
+		t.Fatal(err)
+	}
+	a = &File{}
+	if err = Unmarshal(file, a); err != nil {
+		t.Fatal(err)
+	}
+	b = &File{}
+	if err = Unmarshal(file, b); err != nil {
+		t.Fatal(err)
+	}
+
+	f64 := b.Float64s()
+	for i := range f64 {
+		f64[i] *= 0.3
+	}
+	b.setFloat64s(f64)
+	f64 = b.Float64s()
+	for i := range f64 {
+		f64[i] /= 0.3
+	}
+	b.setFloat64s(f64)
+
+	if bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Fatalf("round trip must introduce rounding errors")
+	}
+	if !a.CloseTo(b, 1e-4) {
+		t.Fatalf("expected: true actual: false")
+	}
+	if a.CloseTo(b, 0) {
+		t.Fatalf("expected: false actual: true")
+	}
+
+	c, _ := New(a.SamplesPerSec(), a.BitsPerSample(), 2)
+	if a.CloseTo(c, 1) {
+		t.Fatalf("expected: false actual: true")
+	}
+	return
+}
+
+func TestFloat32s(t *testing.T) {
+	filenames := []string{
+		"./testdata/sawtooth.wav",
+		"./testdata/44100Hz-32bit-2ch-float.wav",
+	}
+	for _, filename := range filenames {
+		file, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		audio := &File{}
+		if err = Unmarshal(file, audio); err != nil {
+			t.Fatal(err)
+		}
+
+		f64 := audio.Float64s()
+		f32 := audio.Float32s()
+
+		if len(f64) != len(f32) {
+			t.Fatalf("expected: %d actual: %d (%v)", len(f64), len(f32), filename)
+		}
+		for i := range f64 {
+			if math.Abs(float64(f32[i])-f64[i]) > 1e-7 {
+				t.Fatalf("[%v] expected: %v actual: %v (%v)", i, f64[i], f32[i], filename)
+			}
+		}
+	}
+
+	// Only the samples which are actually parsed from the truncated stream are decoded.
+	file, err := ioutil.ReadFile("./testdata/44100Hz-32bit-2ch-float.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio := &File{}
+	if err = Unmarshal(file[:len(file)-8], audio); err != nil {
+		t.Fatal(err)
+	}
+	_, actual := audio.DeclaredVsActual()
+	if f32, f64 := audio.Float32s(), audio.Float64s(); len(f32) != actual/4 || len(f64) != actual/4 {
+		t.Fatalf("expected: %v actual: %v %v", actual/4, len(f32), len(f64))
+	}
+	return
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	file, _ := ioutil.ReadFile("./testdata/sawtooth.wav")
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		Unmarshal(file, &File{})
+	}
+}
+
+func BenchmarkUnmarshalShared(b *testing.B) {
+	file, _ := ioutil.ReadFile("./testdata/sawtooth.wav")
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		UnmarshalShared(file, &File{})
+	}
+}
+
+func BenchmarkInt32s(b *testing.B) {
+	audio, _ := NewWave(Sawtooth, 441, time.Second, 44100, 16, 2)
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		audio.Int32s()
+	}
+}
+
+func BenchmarkInt32s_1M(b *testing.B) {
+	audio, _ := New(44100, 32, 1)
+	audio.Write(make([]byte, 4*1000000))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		audio.Int32s()
+	}
+}
+
+func BenchmarkRead(b *testing.B) {
+	file, _ := ioutil.ReadFile("./testdata/sawtooth.wav")
+	audio := &File{}
+	Unmarshal(file, audio)
+	p := make([]byte, 4096)
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		audio.offset = 0
+		for {
+			if _, err := audio.Read(p); err != nil {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkWrite(b *testing.B) {
+	p := make([]byte, 4096)
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		audio, _ := New(44100, 16, 2)
+		for j := 0; j < 16; j++ {
+			audio.Write(p)
+		}
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	file, _ := ioutil.ReadFile("./testdata/sawtooth.wav")
+	audio := &File{}
+	Unmarshal(file, audio)
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		Marshal(audio)
+	}
+}
+
+func BenchmarkMarshalTo(b *testing.B) {
+	file, _ := ioutil.ReadFile("./testdata/sawtooth.wav")
+	audio := &File{}
+	Unmarshal(file, audio)
+	buf := new(bytes.Buffer)
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		MarshalTo(audio, buf)
+	}
+}
+
+func TestFloatFrames(t *testing.T) {
+	file, err := ioutil.ReadFile("./testdata/44100Hz-32bit-2ch-float.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio := &File{}
+	if err = Unmarshal(file, audio); err != nil {
+		t.Fatal(err)
+	}
+
+	frames := audio.FloatFrames()
+	if len(frames) != audio.Frames() {
+		t.Fatalf("expected: %v actual: %v", audio.Frames(), len(frames))
+	}
+
+	f64 := audio.Float64s()
+	for i, frame := range frames {
+		if len(frame) != 2 {
+			t.Fatalf("[%v] expected: 2 actual: %v", i, len(frame))
+		}
+		if frame[0] != f64[i*2] || frame[1] != f64[i*2+1] {
+			t.Fatalf("[%v] expected: %v actual: %v", i, f64[i*2:i*2+2], frame)
+		}
+	}
+	return
+}
+
+func TestFloat64sRange(t *testing.T) {
+	audio := newTestFile(t, 8000, 2, 8000*3, sine(440, 8000))
+
+	f64, err := audio.Float64sRange(time.Second, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f64) != 8000*2 {
+		t.Fatalf("expected: %v actual: %v", 8000*2, len(f64))
+	}
+	expected := audio.Float64s()[8000*2 : 8000*4]
+	for i := range expected {
+		if f64[i] != expected[i] {
+			t.Fatalf("[%v] expected: %v actual: %v", i, expected[i], f64[i])
+		}
+	}
+
+	if _, err = audio.Float64sRange(2*time.Second, time.Second); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if _, err = audio.Float64sRange(0, 4*time.Second); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
+
+func TestPlanarInt16(t *testing.T) {
+	left := []int16{0, 100, -100, math.MaxInt16}
+	right := []int16{1, -1, math.MinInt16, 42}
+
+	audio, err := NewFromPlanarInt16([][]int16{left, right}, 44100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audio.Channels() != 2 || audio.Frames() != 4 {
+		t.Fatalf("expected: 2 channels and 4 frames actual: %v", audio)
+	}
+
+	planes := audio.PlanarInt16()
+	if fmt.Sprint(planes) != fmt.Sprint([][]int16{left, right}) {
+		t.Fatalf("expected: %v actual: %v", [][]int16{left, right}, planes)
+	}
+
+	interleaved := []int32{0, 1, 100, -1, -100, math.MinInt16, math.MaxInt16, 42}
+	for i, s := range audio.Int32s() {
+		if s != interleaved[i]<<16 {
+			t.Fatalf("[%v] expected: %v actual: %v", i, interleaved[i]<<16, s)
+		}
+	}
+
+	if _, err = NewFromPlanarInt16([][]int16{left, right[:3]}, 44100); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if _, err = NewFromPlanarInt16(nil, 44100); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}
+
+func TestNewFromFloat64s(t *testing.T) {
+	samples := []float64{0, 0.5, -0.5, 0.25, 1, -1, 1.5, -1.5}
+	expected := []float64{0, 0.5, -0.5, 0.25, 1, -1, 1, -1}
+
+	for _, bits := range []int{8, 16, 24, 32} {
+		audio, err := NewFromFloat64s(samples, 44100, bits, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if audio.Frames() != 4 {
+			t.Fatalf("expected: 4 actual: %v (%v bit)", audio.Frames(), bits)
+		}
+
+		epsilon := 1 / math.Ldexp(1, bits-2)
+		for i, f := range audio.Float64s() {
+			if math.Abs(f-expected[i]) > epsilon {
+				t.Fatalf("[%v] expected: %v actual: %v (%v bit)", i, expected[i], f, bits)
+			}
+		}
+	}
+
+	if _, err := NewFromFloat64s(samples[:3], 44100, 16, 2); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	if _, err := NewFromFloat64s(samples, 44100, 16, 0); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	for _, bits := range []int{0, 12, 64} {
+		if _, err := NewFromFloat64s(samples, 44100, bits, 2); err == nil {
+			t.Fatalf("error must not be nil (%v bit)", bits)
+		}
+	}
+	return
+}
+
+func TestNewFromInt16s(t *testing.T) {
+	samples := []int16{0, 1, -1, 1000, -1000, math.MaxInt16, math.MinInt16, 42}
+
+	audio, err := NewFromInt16s(samples, 44100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audio.BitsPerSample() != 16 || audio.Channels() != 2 || audio.Frames() != 4 {
+		t.Fatalf("expected: 16 bit 2 channels 4 frames actual: %v", audio)
+	}
+	if audio.AvgBytesPerSec() != 44100*4 {
+		t.Fatalf("expected: %v actual: %v", 44100*4, audio.AvgBytesPerSec())
+	}
+	if fmt.Sprint(samples) != fmt.Sprint(audio.Int16s()) {
+		t.Fatalf("expected: %v actual: %v", samples, audio.Int16s())
+	}
+
+	if _, err = NewFromInt16s(samples[:3], 44100, 2); err == nil {
+		t.Fatalf("error must not be nil")
+	}
+	return
+}